@@ -0,0 +1,303 @@
+package yandexgpt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Budget caps the tokens or the cost of the completions and image
+// generations of a client.
+type Budget struct {
+	// Name identifies the budget in errors and in Client.Budgets.
+	Name string
+	// Tags limits the budget to calls tagged with all of these tags by
+	// WithTags. By default the budget applies to every call.
+	Tags map[string]string
+	// PerTag makes the budget apply separately to each value of this tag,
+	// such as a budget per team. Calls without the tag are not limited.
+	PerTag string
+	// MaxTokens caps input plus completion tokens. Zero means no cap.
+	MaxTokens int
+	// MaxCost caps the cost computed with Prices. Zero means no cap.
+	MaxCost float64
+	// Prices defaults to DefaultPriceTable.
+	Prices PriceTable
+	// Window starts the budget over every Window. Windows are aligned to
+	// multiples of Window since the zero time, so 24*time.Hour resets at
+	// midnight UTC. Zero never starts over.
+	Window time.Duration
+}
+
+// ErrBudgetExceeded is matched by BudgetExceededError.
+var ErrBudgetExceeded = errors.New("yandexgpt: budget exceeded")
+
+// BudgetExceededError is returned without sending a request when the call
+// could take a budget over its cap.
+type BudgetExceededError struct {
+	YandexGPTError
+	Budget string
+	// Key is the value of the Budget.PerTag tag of the call, if any.
+	Key string
+	// Tokens is true when MaxTokens was hit and false when MaxCost was.
+	Tokens bool
+	// Limit, Used and Estimated are in tokens or in currency units. Used
+	// includes calls in progress at their estimate.
+	Limit, Used, Estimated float64
+	// ResetAt is the start of the next window, or zero without a Window.
+	ResetAt time.Time
+}
+
+func (e *BudgetExceededError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+// BudgetUsage is the state of a budget in its current window.
+type BudgetUsage struct {
+	Budget string
+	Key    string
+	// WindowStart is zero for budgets without a Window.
+	WindowStart time.Time
+	Tokens      int
+	Cost        float64
+	// ReservedTokens and ReservedCost are the estimates of calls in
+	// progress.
+	ReservedTokens int
+	ReservedCost   float64
+}
+
+// WithBudget adds budgets to the client. Before a completion or an image
+// generation, its tokens are estimated as EstimateTokens of the messages
+// plus CompletionOptions.MaxTokens and reserved in every budget it falls
+// under; a call that could exceed a cap fails with a BudgetExceededError.
+// Once the response arrives, the reservation is replaced by the actual
+// Usage; failed calls give it back. Derived clients share the budgets.
+func WithBudget(budgets ...Budget) ClientOption {
+	return func(c *Client) {
+		for _, b := range budgets {
+			if b.Prices == nil {
+				b.Prices = DefaultPriceTable()
+			}
+			c.budgets = append(c.budgets, &budget{Budget: b, usage: map[string]*BudgetUsage{}})
+		}
+	}
+}
+
+// Budgets returns the usage of every budget of c, in the order they were
+// added and by key.
+func (c *Client) Budgets() []BudgetUsage {
+	var result []BudgetUsage
+	for _, b := range c.budgets {
+		result = append(result, b.snapshot(time.Now())...)
+	}
+	return result
+}
+
+type budget struct {
+	Budget
+
+	mu    sync.Mutex
+	usage map[string]*BudgetUsage
+}
+
+// budgets are the budgets of a client.
+type budgets []*budget
+
+// budgetCharge is the tokens and cost of a call in one budget.
+type budgetCharge struct {
+	budget *budget
+	key    string
+	tokens int
+	cost   float64
+}
+
+// budgetReservation holds the estimates of a call until it is settled with
+// the actual usage or released.
+type budgetReservation struct {
+	charges []budgetCharge
+	done    bool
+}
+
+// reserve reserves the estimated usage of a call in the budgets it falls
+// under. It returns nil for calls that no budget limits.
+func (bs budgets) reserve(call *callOptions, body interface{}) (*budgetReservation, error) {
+	if len(bs) == 0 {
+		return nil, nil
+	}
+	model := requestModel(body)
+	var tokens, inputTokens, images int
+	switch request := body.(type) {
+	case *CompletionRequest:
+		for _, m := range request.Messages {
+			inputTokens += EstimateTokens(m.Text)
+		}
+		tokens = inputTokens + request.CompletionOptions.MaxTokens
+	case *ImageGenerationRequest:
+		images = 1
+	default:
+		return nil, nil
+	}
+
+	now := time.Now()
+	reservation := &budgetReservation{}
+	for _, b := range bs {
+		key, ok := b.applies(call.tags)
+		if !ok {
+			continue
+		}
+		price := b.Prices[model]
+		charge := budgetCharge{
+			budget: b,
+			key:    key,
+			tokens: tokens,
+			cost: float64(inputTokens)/1000*price.InputPer1K +
+				float64(tokens-inputTokens)/1000*price.CompletionPer1K +
+				float64(images)*price.PerImage,
+		}
+		if err := b.reserve(charge, now); err != nil {
+			reservation.release()
+			return nil, err
+		}
+		reservation.charges = append(reservation.charges, charge)
+	}
+	return reservation, nil
+}
+
+// settle replaces the estimates with the actual usage of a successful call.
+func (r *budgetReservation) settle(body, result interface{}) {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+
+	var usage Usage
+	var images int
+	switch response := result.(type) {
+	case *CompletionResponse:
+		usage = response.Result.Usage
+	case *Operation:
+		images = 1
+	}
+	model := requestModel(body)
+	now := time.Now()
+	for _, charge := range r.charges {
+		price := charge.budget.Prices[model]
+		charge.budget.settle(charge, now,
+			usage.InputTextTokens+usage.CompletionTokens,
+			float64(usage.InputTextTokens)/1000*price.InputPer1K+
+				float64(usage.CompletionTokens)/1000*price.CompletionPer1K+
+				float64(images)*price.PerImage,
+		)
+	}
+}
+
+// release gives back the estimates of a failed call.
+func (r *budgetReservation) release() {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	now := time.Now()
+	for _, charge := range r.charges {
+		charge.budget.settle(charge, now, 0, 0)
+	}
+}
+
+// applies reports whether calls with tags fall under b, and under which key.
+func (b *budget) applies(tags map[string]string) (string, bool) {
+	for k, v := range b.Tags {
+		if tags[k] != v {
+			return "", false
+		}
+	}
+	if b.PerTag == "" {
+		return "", true
+	}
+	key, ok := tags[b.PerTag]
+	return key, ok
+}
+
+// current returns the usage of key in the window of now, starting a new
+// window if needed. It must be called with b.mu held.
+func (b *budget) current(key string, now time.Time) *BudgetUsage {
+	var start time.Time
+	if b.Window > 0 {
+		start = now.Truncate(b.Window)
+	}
+	u, ok := b.usage[key]
+	if !ok {
+		u = &BudgetUsage{Budget: b.Name, Key: key, WindowStart: start}
+		b.usage[key] = u
+	} else if !u.WindowStart.Equal(start) {
+		// Reservations of calls still in progress carry over.
+		u.WindowStart, u.Tokens, u.Cost = start, 0, 0
+	}
+	return u
+}
+
+func (b *budget) reserve(charge budgetCharge, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u := b.current(charge.key, now)
+	if b.MaxTokens > 0 && u.Tokens+u.ReservedTokens+charge.tokens > b.MaxTokens {
+		return b.exceeded(charge.key, true, float64(b.MaxTokens), float64(u.Tokens+u.ReservedTokens), float64(charge.tokens), u.WindowStart)
+	}
+	if b.MaxCost > 0 && u.Cost+u.ReservedCost+charge.cost > b.MaxCost {
+		return b.exceeded(charge.key, false, b.MaxCost, u.Cost+u.ReservedCost, charge.cost, u.WindowStart)
+	}
+	u.ReservedTokens += charge.tokens
+	u.ReservedCost += charge.cost
+	return nil
+}
+
+func (b *budget) settle(charge budgetCharge, now time.Time, tokens int, cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	u := b.current(charge.key, now)
+	u.ReservedTokens -= charge.tokens
+	u.ReservedCost -= charge.cost
+	u.Tokens += tokens
+	u.Cost += cost
+}
+
+func (b *budget) exceeded(key string, tokens bool, limit, used, estimated float64, windowStart time.Time) *BudgetExceededError {
+	resource := "cost"
+	if tokens {
+		resource = "tokens"
+	}
+	name := b.Name
+	if key != "" {
+		name += "/" + key
+	}
+	err := &BudgetExceededError{
+		YandexGPTError: YandexGPTError{Message: fmt.Sprintf("budget %q exceeded: %s used %g of %g, call needs up to %g", name, resource, used, limit, estimated)},
+		Budget:         b.Name,
+		Key:            key,
+		Tokens:         tokens,
+		Limit:          limit,
+		Used:           used,
+		Estimated:      estimated,
+	}
+	if b.Window > 0 {
+		err.ResetAt = windowStart.Add(b.Window)
+	}
+	return err
+}
+
+func (b *budget) snapshot(now time.Time) []BudgetUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0, len(b.usage))
+	for key := range b.usage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]BudgetUsage, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *b.current(key, now))
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package yandexgpt
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestBudgetTokens(t *testing.T) {
+	var calls int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeCompletion(w, "ok")
+	},
+		WithBudget(Budget{Name: "daily", MaxTokens: 110, Window: 24 * time.Hour}),
+	)
+
+	// Each call reserves 2 input tokens plus 100 completion tokens and then
+	// uses 3 tokens according to writeCompletion.
+	options := &CompletionOptions{MaxTokens: 100}
+	for i := 0; i < 3; i++ {
+		if _, err := client.GenerateText("Hi", models.YandexGPTLite, options); err != nil {
+			t.Fatalf("Call %d: %v", i, err)
+		}
+	}
+
+	usage := client.Budgets()
+	if len(usage) != 1 || usage[0].Tokens != 9 || usage[0].ReservedTokens != 0 || !usage[0].WindowStart.Equal(time.Now().Truncate(24*time.Hour)) {
+		t.Fatalf("Unexpected budget usage: %+v", usage)
+	}
+
+	_, err := client.GenerateText("Hi", models.YandexGPTLite, &CompletionOptions{MaxTokens: 200})
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected a BudgetExceededError, got %v", err)
+	}
+	if budgetErr.Budget != "daily" || !budgetErr.Tokens || budgetErr.Limit != 110 || budgetErr.Used != 9 || budgetErr.Estimated != 201 {
+		t.Errorf("Unexpected error: %+v", budgetErr)
+	}
+	if !budgetErr.ResetAt.Equal(usage[0].WindowStart.Add(24 * time.Hour)) {
+		t.Errorf("Unexpected ResetAt %v", budgetErr.ResetAt)
+	}
+	if calls != 3 {
+		t.Errorf("Expected the rejected call not to be sent, got %d requests", calls)
+	}
+}
+
+func TestBudgetCostPerTag(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeCompletion(w, "ok")
+	},
+		WithBudget(Budget{
+			Name:    "teams",
+			PerTag:  "team",
+			MaxCost: 1,
+			Prices:  PriceTable{models.YandexGPTLite: {InputPer1K: 1000, CompletionPer1K: 1000}},
+		}),
+	)
+
+	search := WithTags(map[string]string{"team": "search"})
+	ads := WithTags(map[string]string{"team": "ads"})
+	options := &CompletionOptions{MaxTokens: 0}
+
+	// A failed call gives its reservation back.
+	if _, err := client.GenerateText("a", models.YandexGPTLite, options, search, WithHeader("X-Fail", "1")); errors.Is(err, ErrBudgetExceeded) {
+		t.Fatal("Expected the API error")
+	}
+	if _, err := client.GenerateText("a", models.YandexGPTLite, options, search); err != nil {
+		t.Fatal(err)
+	}
+	// The search team has spent 3 of 1, the ads team nothing yet.
+	if _, err := client.GenerateText("a", models.YandexGPTLite, options, search); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected the search budget to be exceeded, got %v", err)
+	}
+	if _, err := client.GenerateText("a", models.YandexGPTLite, options, ads); err != nil {
+		t.Errorf("Expected the ads budget to be available, got %v", err)
+	}
+	if _, err := client.GenerateText("a", models.YandexGPTLite, options); err != nil {
+		t.Errorf("Expected untagged calls not to be limited, got %v", err)
+	}
+
+	usage := client.Budgets()
+	if len(usage) != 2 || usage[0].Key != "ads" || usage[1].Key != "search" || usage[1].Cost != 3 || usage[1].ReservedCost != 0 {
+		t.Errorf("Unexpected budget usage: %+v", usage)
+	}
+}
@@ -0,0 +1,338 @@
+package yandexgpt
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache stores completion responses for WithCache. Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key. It returns false if there is
+	// none or it has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl. A zero ttl means no expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CachePolicy configures the response cache of a client. The zero value
+// caches every completion without expiry.
+type CachePolicy struct {
+	// TTL is the lifetime of cached responses. Zero means no expiry.
+	TTL time.Duration
+	// Cacheable reports whether the response to request may be cached and
+	// served from the cache, including the semantic cache of
+	// WithSemanticCache. By default every completion is; use it, for
+	// example, to cache only completions at a low temperature.
+	Cacheable func(request *CompletionRequest) bool
+}
+
+// CacheEvent describes a cache lookup of a completion.
+type CacheEvent struct {
+	// Key is the CacheKey of the request, empty for semantic lookups.
+	Key   string
+	Model string
+	// Semantic reports a lookup in the cache of WithSemanticCache.
+	Semantic bool
+	// Similarity is the cosine similarity of the nearest cached prompt of a
+	// semantic lookup.
+	Similarity float64
+	Hit        bool
+	// Err is the error of the cache or of the embedder, if any. Failed
+	// lookups are misses.
+	Err error
+}
+
+type responseCache struct {
+	cache  Cache
+	policy CachePolicy
+}
+
+// WithCache caches the responses of completions in cache, keyed by
+// CacheKey. A cached response is returned without calling the API; its
+// ResponseMetadata reports Cached. Responses blocked by the content filter
+// are not cached. Lookups are reported to Hooks.OnCacheLookup; errors of
+// the cache never fail a call.
+//
+// The cache is opt-in per client and suits identical prompts at a low
+// temperature; WithCacheBypass and WithCacheRefresh control single calls.
+func WithCache(cache Cache, policy *CachePolicy) ClientOption {
+	return func(c *Client) {
+		if cache == nil {
+			c.cache = nil
+			return
+		}
+		rc := &responseCache{cache: cache}
+		if policy != nil {
+			rc.policy = *policy
+		}
+		c.cache = rc
+	}
+}
+
+// cacheMode is the cache behavior of a call.
+type cacheMode int
+
+const (
+	cacheDefault cacheMode = iota
+	cacheBypass
+	cacheRefresh
+)
+
+// WithCacheBypass neither reads nor writes the response cache.
+func WithCacheBypass() CallOption {
+	return func(o *callOptions) {
+		o.cache = cacheBypass
+	}
+}
+
+// WithCacheRefresh skips the cached response, if any, and caches the new
+// one in its place.
+func WithCacheRefresh() CallOption {
+	return func(o *callOptions) {
+		o.cache = cacheRefresh
+	}
+}
+
+// CacheKey returns the cache key of request: a hash of its model URI,
+// completion options and messages.
+func CacheKey(request *CompletionRequest) string {
+	return requestKey(CallCompletion, request)
+}
+
+// cachedCompletion sends request through send unless its response is in the
+// exact or the semantic cache, and caches the response.
+func (c *Client) cachedCompletion(ctx context.Context, call *callOptions, request *CompletionRequest, send func(context.Context) (*CompletionResponse, error)) (*CompletionResponse, error) {
+	if call.cache == cacheBypass {
+		return send(ctx)
+	}
+	// Requests the policy does not cache skip the semantic cache too.
+	rc := c.cache
+	if rc != nil && rc.policy.Cacheable != nil && !rc.policy.Cacheable(request) {
+		return send(ctx)
+	}
+	lookup := call.cache != cacheRefresh
+	model := requestModel(request)
+
+	var key string
+	if rc != nil {
+		key = CacheKey(request)
+		if lookup {
+			data, ok, err := rc.cache.Get(ctx, key)
+			var response CompletionResponse
+			if ok && err == nil {
+				err = json.Unmarshal(data, &response)
+			}
+			if hit := c.cacheLookup(CacheEvent{Key: key, Model: model, Hit: ok && err == nil, Err: err}); hit {
+				return c.cachedResponse(call, &response), nil
+			}
+		}
+	}
+
+	query, err := c.semanticCache.query(ctx, request)
+	if err != nil {
+		c.cacheLookup(CacheEvent{Model: model, Semantic: true, Err: err})
+	}
+	if query != nil && lookup {
+		response, similarity, err := c.semanticCache.lookup(ctx, query)
+		if hit := c.cacheLookup(CacheEvent{Model: model, Semantic: true, Similarity: similarity, Hit: response != nil, Err: err}); hit {
+			// Serve later identical requests from the exact cache too.
+			c.storeCached(ctx, rc, key, response)
+			return c.cachedResponse(call, response), nil
+		}
+	}
+
+	response, err := send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if contentFilterError(response) == nil {
+		c.storeCached(ctx, rc, key, response)
+		if query != nil {
+			c.semanticCache.store(ctx, query, response)
+		}
+	}
+	return response, nil
+}
+
+// cacheLookup reports a lookup to the hooks and returns whether it hit.
+func (c *Client) cacheLookup(event CacheEvent) bool {
+	if c.hooks.OnCacheLookup != nil {
+		c.hooks.OnCacheLookup(event)
+	}
+	return event.Hit
+}
+
+func (c *Client) cachedResponse(call *callOptions, response *CompletionResponse) *CompletionResponse {
+	response.setMetadata(&ResponseMetadata{Tags: call.tags, Cached: true})
+	return response
+}
+
+func (c *Client) storeCached(ctx context.Context, rc *responseCache, key string, response *CompletionResponse) {
+	if rc == nil {
+		return
+	}
+	if data, err := json.Marshal(response); err == nil {
+		rc.cache.Set(ctx, key, data, rc.policy.TTL)
+	}
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used
+// entries beyond its capacity.
+type MemoryCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns a cache holding up to maxEntries responses, or any
+// number if maxEntries is not positive.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+// Get implements Cache.
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		m.remove(element)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set implements Cache.
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.lru.MoveToFront(element)
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(entry)
+	if m.maxEntries > 0 && m.lru.Len() > m.maxEntries {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+func (m *MemoryCache) remove(element *list.Element) {
+	m.lru.Remove(element)
+	delete(m.entries, element.Value.(*memoryCacheEntry).key)
+}
+
+// FileCache is a Cache that keeps every entry in a file of a directory, so
+// cached responses survive restarts and can be shared by processes.
+type FileCache struct {
+	dir string
+}
+
+type fileCacheEntry struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	Value     []byte    `json:"value"`
+}
+
+// NewFileCache returns a cache storing entries in dir, which is created if
+// needed.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("yandexgpt: create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (f *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Cache. Expired entries are deleted.
+func (f *FileCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path := f.path(key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("yandexgpt: read cache entry: %w", err)
+	}
+
+	var entry fileCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("yandexgpt: decode cache entry: %w", err)
+	}
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		os.Remove(path)
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// Set implements Cache. The entry is written to a temporary file first, so
+// readers never see a partial entry.
+func (f *FileCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := fileCacheEntry{Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("yandexgpt: encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(f.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("yandexgpt: write cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("yandexgpt: write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("yandexgpt: write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(key)); err != nil {
+		return fmt.Errorf("yandexgpt: write cache entry: %w", err)
+	}
+	return nil
+}
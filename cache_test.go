@@ -0,0 +1,132 @@
+package yandexgpt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestCache(t *testing.T) {
+	var calls int32
+	var events []CacheEvent
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, fmt.Sprintf("answer %d", atomic.AddInt32(&calls, 1)))
+	},
+		WithCache(NewMemoryCache(10), &CachePolicy{
+			Cacheable: func(r *CompletionRequest) bool { return r.CompletionOptions.Temperature <= 0.3 },
+		}),
+		WithHooks(Hooks{OnCacheLookup: func(e CacheEvent) { events = append(events, e) }}),
+	)
+
+	cold := &CompletionOptions{Temperature: 0, MaxTokens: 100}
+	generate := func(prompt string, options *CompletionOptions, opts ...CallOption) *CompletionResponse {
+		t.Helper()
+		response, err := client.GenerateText(prompt, models.YandexGPTLite, options, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	first := generate("Hello", cold)
+	second := generate("Hello", cold, WithTags(map[string]string{"team": "search"}))
+	if second.Result.Alternatives[0].Message.Text != "answer 1" || calls != 1 {
+		t.Fatalf("Expected a cached answer, got %q after %d calls", second.Result.Alternatives[0].Message.Text, calls)
+	}
+	if first.ResponseMetadata().Cached {
+		t.Error("Expected the first response not to be cached")
+	}
+	metadata := second.ResponseMetadata()
+	if !metadata.Cached || metadata.Model != models.YandexGPTLite || metadata.Tags["team"] != "search" {
+		t.Errorf("Unexpected metadata of a cached response: %+v", metadata)
+	}
+	if len(events) != 2 || events[0].Hit || !events[1].Hit || events[1].Model != models.YandexGPTLite || events[1].Key != CacheKey(&CompletionRequest{
+		ModelURI:          "gpt://test_folder/yandexgpt-lite",
+		CompletionOptions: *cold,
+		Messages:          []Message{{Role: "user", Text: "Hello"}},
+	}) {
+		t.Errorf("Unexpected cache events: %+v", events)
+	}
+
+	if text := generate("Hello", cold, WithCacheBypass()).Result.Alternatives[0].Message.Text; text != "answer 2" {
+		t.Errorf("Expected a bypassed call to reach the API, got %q", text)
+	}
+	if text := generate("Hello", cold, WithCacheRefresh()).Result.Alternatives[0].Message.Text; text != "answer 3" {
+		t.Errorf("Expected a refreshed call to reach the API, got %q", text)
+	}
+	if text := generate("Hello", cold).Result.Alternatives[0].Message.Text; text != "answer 3" {
+		t.Errorf("Expected the refreshed answer to be cached, got %q", text)
+	}
+
+	warm := &CompletionOptions{Temperature: 0.9, MaxTokens: 100}
+	generate("Hello", warm)
+	if text := generate("Hello", warm).Result.Alternatives[0].Message.Text; text != "answer 5" {
+		t.Errorf("Expected uncacheable calls to reach the API, got %q", text)
+	}
+	if calls != 5 {
+		t.Errorf("Expected 5 API calls, got %d", calls)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2)
+	cache.Set(ctx, "a", []byte("1"), 0)
+	cache.Set(ctx, "b", []byte("2"), 0)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), 0)
+
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if value, ok, _ := cache.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Errorf("Expected a to be kept, got %q", value)
+	}
+
+	cache.Set(ctx, "d", []byte("4"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := cache.Get(ctx, "d"); ok {
+		t.Error("Expected an expired entry to be missing")
+	}
+	// Adding d evicted c before d expired.
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", cache.Len())
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cache, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Set(ctx, "completion:abc", []byte(`{"result":{}}`), 0); err != nil {
+		t.Fatal(err)
+	}
+	reopened, _ := NewFileCache(dir)
+	if value, ok, err := reopened.Get(ctx, "completion:abc"); err != nil || !ok || string(value) != `{"result":{}}` {
+		t.Errorf("Expected the entry to persist, got %q, %v, %v", value, ok, err)
+	}
+
+	cache.Set(ctx, "expired", []byte("x"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := cache.Get(ctx, "expired"); ok {
+		t.Error("Expected an expired entry to be missing")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the expired entry to be deleted, got %d files", len(entries))
+	}
+
+	if _, ok, err := cache.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Expected a plain miss, got %v, %v", ok, err)
+	}
+}
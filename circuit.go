@@ -0,0 +1,384 @@
+package yandexgpt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint names a family of API endpoints that share a circuit breaker.
+type Endpoint string
+
+const (
+	// EndpointCompletion covers completions, tokenization, embeddings and
+	// classification.
+	EndpointCompletion    Endpoint = "completion"
+	EndpointImage         Endpoint = "image"
+	EndpointOperations    Endpoint = "operations"
+	EndpointConversations Endpoint = "conversations"
+	// EndpointIAM covers the exchange of OAuth tokens for IAM tokens. Calls
+	// served with a cached IAM token do not pass through its breaker.
+	EndpointIAM Endpoint = "iam"
+)
+
+// endpointOf returns the endpoint family of an API URL.
+func endpointOf(requestURL string) Endpoint {
+	switch {
+	case strings.HasPrefix(requestURL, conversationsBaseURL):
+		return EndpointConversations
+	case strings.HasPrefix(requestURL, OperationsEndpoint):
+		return EndpointOperations
+	case strings.HasPrefix(requestURL, ImageGenerationAsyncEndpoint):
+		return EndpointImage
+	default:
+		return EndpointCompletion
+	}
+}
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through and counts their failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests with a CircuitOpenError.
+	CircuitOpen
+	// CircuitHalfOpen lets a few probe requests through to decide whether
+	// the circuit closes again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// Circuit breaker defaults used when the corresponding CircuitBreakerPolicy
+// field is zero.
+const (
+	DefaultCircuitConsecutiveFailures = 5
+	DefaultCircuitMinRequests         = 20
+	DefaultCircuitInterval            = time.Minute
+	DefaultCircuitOpenTimeout         = 30 * time.Second
+)
+
+// CircuitBreakerPolicy configures the circuit breakers of a client. Each
+// endpoint family has its own breaker, so an outage of image generation
+// does not stop completions.
+type CircuitBreakerPolicy struct {
+	// ConsecutiveFailures opens the circuit after this many failures in a
+	// row. Defaults to DefaultCircuitConsecutiveFailures.
+	ConsecutiveFailures int
+	// FailureRatio opens the circuit when this share of the requests of the
+	// current Interval failed, once MinRequests were made. Zero disables it.
+	FailureRatio float64
+	// MinRequests defaults to DefaultCircuitMinRequests.
+	MinRequests int
+	// Interval is how often the counts of a closed circuit are cleared.
+	// Defaults to DefaultCircuitInterval.
+	Interval time.Duration
+	// OpenTimeout is how long the circuit stays open before probe requests
+	// are let through. Defaults to DefaultCircuitOpenTimeout.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of concurrent probes in the half-open
+	// state. Defaults to 1.
+	HalfOpenRequests int
+	// IsFailure reports whether err counts as a failure. Defaults to
+	// IsCircuitFailure. Requests whose context is done are never counted.
+	IsFailure func(err error) bool
+	// OnStateChange, if set, is called after every state change. It must not
+	// block.
+	OnStateChange func(CircuitEvent)
+}
+
+// CircuitEvent describes a state change of a circuit breaker.
+type CircuitEvent struct {
+	Endpoint Endpoint
+	From     CircuitState
+	To       CircuitState
+	Time     time.Time
+	// Err is the failure that opened the circuit, if it opened.
+	Err error
+}
+
+// WithCircuitBreaker protects every endpoint family of the client with a
+// circuit breaker. Clients derived from it share the breakers.
+func WithCircuitBreaker(policy CircuitBreakerPolicy) ClientOption {
+	return func(c *Client) {
+		c.breakers = newCircuitBreakers(policy)
+	}
+}
+
+// ErrCircuitOpen is matched by CircuitOpenError.
+var ErrCircuitOpen = errors.New("yandexgpt: circuit open")
+
+// CircuitOpenError is returned without sending a request while the circuit
+// of the endpoint is open.
+type CircuitOpenError struct {
+	YandexGPTError
+	Endpoint Endpoint
+	// RetryAfter is the time until probe requests are let through again.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+// IsCircuitFailure reports whether err suggests that the endpoint is down:
+// a server error or a request that got no response at all.
+func IsCircuitFailure(err error) bool {
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	if metadata := ErrorMetadata(err); metadata != nil && metadata.StatusCode >= 500 {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// CircuitState returns the state of the circuit breaker of endpoint. It is
+// CircuitClosed when the client has no circuit breaker.
+func (c *Client) CircuitState(endpoint Endpoint) CircuitState {
+	if c.breakers == nil {
+		return CircuitClosed
+	}
+	return c.breakers.get(endpoint).state(time.Now())
+}
+
+type circuitBreakers struct {
+	policy CircuitBreakerPolicy
+
+	mu       sync.Mutex
+	circuits map[Endpoint]*circuit
+}
+
+func newCircuitBreakers(policy CircuitBreakerPolicy) *circuitBreakers {
+	if policy.ConsecutiveFailures <= 0 {
+		policy.ConsecutiveFailures = DefaultCircuitConsecutiveFailures
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = DefaultCircuitMinRequests
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = DefaultCircuitInterval
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = DefaultCircuitOpenTimeout
+	}
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = 1
+	}
+	if policy.IsFailure == nil {
+		policy.IsFailure = IsCircuitFailure
+	}
+	return &circuitBreakers{policy: policy, circuits: make(map[Endpoint]*circuit)}
+}
+
+func (b *circuitBreakers) get(endpoint Endpoint) *circuit {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, ok := b.circuits[endpoint]
+	if !ok {
+		cb = &circuit{endpoint: endpoint, policy: &b.policy}
+		b.circuits[endpoint] = cb
+	}
+	return cb
+}
+
+// allow returns a pass for a request to endpoint if it may be sent, or a
+// CircuitOpenError. The result of the request must be passed to record or,
+// if it was not sent, to release. Without breakers it returns nil, on which
+// record and release do nothing.
+func (b *circuitBreakers) allow(endpoint Endpoint) (*circuitPass, error) {
+	if b == nil {
+		return nil, nil
+	}
+	return b.get(endpoint).allow(time.Now())
+}
+
+type circuit struct {
+	endpoint Endpoint
+	policy   *CircuitBreakerPolicy
+
+	mu          sync.Mutex
+	current     CircuitState
+	openedUntil time.Time
+	resetAt     time.Time
+	requests    int
+	failures    int
+	consecutive int
+	probes      int
+	// period counts the state changes, so results of requests let through
+	// in an earlier state are not counted.
+	period int
+}
+
+// circuitPass is a request let through by a circuit.
+type circuitPass struct {
+	circuit *circuit
+	period  int
+	probe   bool
+}
+
+// state returns the current state, moving an open circuit whose timeout
+// has passed to half-open.
+func (c *circuit) state(now time.Time) CircuitState {
+	c.mu.Lock()
+	state, event := c.advance(now)
+	c.mu.Unlock()
+	c.emit(event)
+	return state
+}
+
+// advance applies the state changes due at now. The caller must hold c.mu
+// and emit the returned event after unlocking it.
+func (c *circuit) advance(now time.Time) (CircuitState, *CircuitEvent) {
+	switch c.current {
+	case CircuitOpen:
+		if !now.Before(c.openedUntil) {
+			return CircuitHalfOpen, c.transition(CircuitHalfOpen, now, nil)
+		}
+	case CircuitClosed:
+		if !now.Before(c.resetAt) {
+			c.requests, c.failures = 0, 0
+			c.resetAt = now.Add(c.policy.Interval)
+		}
+	}
+	return c.current, nil
+}
+
+func (c *circuit) transition(to CircuitState, now time.Time, err error) *CircuitEvent {
+	event := &CircuitEvent{Endpoint: c.endpoint, From: c.current, To: to, Time: now, Err: err}
+
+	c.current = to
+	c.period++
+	c.requests, c.failures, c.consecutive, c.probes = 0, 0, 0, 0
+	switch to {
+	case CircuitOpen:
+		c.openedUntil = now.Add(c.policy.OpenTimeout)
+	case CircuitClosed:
+		c.resetAt = now.Add(c.policy.Interval)
+	}
+	return event
+}
+
+func (c *circuit) emit(event *CircuitEvent) {
+	if event != nil && c.policy.OnStateChange != nil {
+		c.policy.OnStateChange(*event)
+	}
+}
+
+func (c *circuit) allow(now time.Time) (*circuitPass, error) {
+	c.mu.Lock()
+	state, event := c.advance(now)
+
+	pass := &circuitPass{circuit: c, period: c.period}
+	var err error
+	switch {
+	case state == CircuitOpen:
+		err = c.openError(now)
+	case state == CircuitHalfOpen && c.probes >= c.policy.HalfOpenRequests:
+		err = c.openError(now)
+	case state == CircuitHalfOpen:
+		c.probes++
+		pass.probe = true
+	}
+	c.mu.Unlock()
+
+	c.emit(event)
+	if err != nil {
+		return nil, err
+	}
+	return pass, nil
+}
+
+func (c *circuit) openError(now time.Time) *CircuitOpenError {
+	retryAfter := c.openedUntil.Sub(now)
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	return &CircuitOpenError{
+		YandexGPTError: YandexGPTError{Message: fmt.Sprintf("circuit breaker for %s endpoint is open", c.endpoint)},
+		Endpoint:       c.endpoint,
+		RetryAfter:     retryAfter,
+	}
+}
+
+// record counts the result of a request let through by allow. Requests
+// whose context is done say nothing about the endpoint and are released.
+// Results of requests let through before the last state change are not
+// counted: in particular, only probes decide on a half-open circuit.
+func (p *circuitPass) record(ctx context.Context, err error) {
+	if p == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		p.release()
+		return
+	}
+
+	c := p.circuit
+	failed := err != nil && c.policy.IsFailure(err)
+	now := time.Now()
+
+	c.mu.Lock()
+	var event *CircuitEvent
+	switch {
+	case p.period != c.period:
+	case c.current == CircuitHalfOpen:
+		if failed {
+			event = c.transition(CircuitOpen, now, err)
+		} else {
+			event = c.transition(CircuitClosed, now, nil)
+		}
+	case c.current == CircuitClosed:
+		c.requests++
+		if failed {
+			c.failures++
+			c.consecutive++
+		} else {
+			c.consecutive = 0
+		}
+		if failed && c.tripped() {
+			event = c.transition(CircuitOpen, now, err)
+		}
+	}
+	c.mu.Unlock()
+
+	c.emit(event)
+}
+
+func (c *circuit) tripped() bool {
+	if c.consecutive >= c.policy.ConsecutiveFailures {
+		return true
+	}
+	return c.policy.FailureRatio > 0 && c.requests >= c.policy.MinRequests &&
+		float64(c.failures)/float64(c.requests) >= c.policy.FailureRatio
+}
+
+// release returns the probe slot of a request that was not sent or whose
+// result is not counted.
+func (p *circuitPass) release() {
+	if p == nil || !p.probe {
+		return
+	}
+	c := p.circuit
+	c.mu.Lock()
+	if p.period == c.period {
+		c.probes--
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,204 @@
+package yandexgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	var calls int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	var events []CircuitEvent
+	client.breakers = newCircuitBreakers(CircuitBreakerPolicy{
+		ConsecutiveFailures: 2,
+		OnStateChange:       func(e CircuitEvent) { events = append(events, e) },
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("Expected ErrUnavailable, got %v", err)
+		}
+	}
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected CircuitOpenError, got %v", err)
+	}
+	if openErr.Endpoint != EndpointCompletion || openErr.RetryAfter <= 0 {
+		t.Errorf("Unexpected error fields: %+v", openErr)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected no request while the circuit is open, got %d requests", n)
+	}
+
+	if len(events) != 1 || events[0].From != CircuitClosed || events[0].To != CircuitOpen || !errors.Is(events[0].Err, ErrUnavailable) {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+	if state := client.CircuitState(EndpointCompletion); state != CircuitOpen {
+		t.Errorf("Expected open completion circuit, got %v", state)
+	}
+	if state := client.CircuitState(EndpointImage); state != CircuitClosed {
+		t.Errorf("Expected closed image circuit, got %v", state)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	var healthy int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeCompletion(w, "ok")
+	})
+
+	var mu sync.Mutex
+	var transitions []string
+	client.breakers = newCircuitBreakers(CircuitBreakerPolicy{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         20 * time.Millisecond,
+		OnStateChange: func(e CircuitEvent) {
+			mu.Lock()
+			transitions = append(transitions, e.To.String())
+			mu.Unlock()
+		},
+	})
+
+	client.GenerateText("Hello", models.YandexGPTLite, nil)
+	time.Sleep(30 * time.Millisecond)
+
+	// The probe fails and opens the circuit again.
+	client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if state := client.CircuitState(EndpointCompletion); state != CircuitOpen {
+		t.Fatalf("Expected open circuit after a failed probe, got %v", state)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(30 * time.Millisecond)
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+	if state := client.CircuitState(EndpointCompletion); state != CircuitClosed {
+		t.Errorf("Expected closed circuit after a successful probe, got %v", state)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(transitions, ","); got != "open,half-open,open,half-open,closed" {
+		t.Errorf("Unexpected transitions: %s", got)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"bad request"}}`))
+	})
+	client.breakers = newCircuitBreakers(CircuitBreakerPolicy{ConsecutiveFailures: 1})
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); !errors.Is(err, ErrInvalidArgument) {
+			t.Fatalf("Expected ErrInvalidArgument, got %v", err)
+		}
+	}
+	if state := client.CircuitState(EndpointCompletion); state != CircuitClosed {
+		t.Errorf("Expected closed circuit, got %v", state)
+	}
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	var calls int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeCompletion(w, "ok")
+	})
+	client.breakers = newCircuitBreakers(CircuitBreakerPolicy{FailureRatio: 0.5, MinRequests: 4})
+
+	for i := 0; i < 4; i++ {
+		client.GenerateText("Hello", models.YandexGPTLite, nil)
+	}
+	if state := client.CircuitState(EndpointCompletion); state != CircuitOpen {
+		t.Errorf("Expected the failure ratio to open the circuit, got %v", state)
+	}
+}
+
+func TestCircuitBreakerCountsOnlyProbes(t *testing.T) {
+	breakers := newCircuitBreakers(CircuitBreakerPolicy{ConsecutiveFailures: 1, OpenTimeout: time.Millisecond})
+	ctx := context.Background()
+
+	late, _ := breakers.allow(EndpointCompletion)
+	failed, _ := breakers.allow(EndpointCompletion)
+	failed.record(ctx, &UnavailableError{APIError: &APIError{}})
+	time.Sleep(5 * time.Millisecond)
+	probe, err := breakers.allow(EndpointCompletion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A success of a request sent before the circuit opened does not close
+	// it, nor does it free the probe slot.
+	late.record(ctx, nil)
+	late.release()
+	circuit := breakers.get(EndpointCompletion)
+	if state := circuit.state(time.Now()); state != CircuitHalfOpen {
+		t.Fatalf("Expected the circuit to stay half-open, got %v", state)
+	}
+	if _, err := breakers.allow(EndpointCompletion); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected a second probe to be rejected, got %v", err)
+	}
+
+	probe.record(ctx, nil)
+	if state := circuit.state(time.Now()); state != CircuitClosed {
+		t.Errorf("Expected the probe to close the circuit, got %v", state)
+	}
+}
+
+func TestCircuitBreakerIAMCachedToken(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "ok")
+	}, WithCircuitBreaker(CircuitBreakerPolicy{}))
+
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Calls made with the cached IAM token do not need the IAM endpoint.
+	circuit := client.breakers.get(EndpointIAM)
+	circuit.mu.Lock()
+	circuit.transition(CircuitOpen, time.Now(), nil)
+	circuit.mu.Unlock()
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Errorf("Expected a call with a cached IAM token to succeed, got %v", err)
+	}
+}
+
+func TestEndpointOf(t *testing.T) {
+	tests := map[string]Endpoint{
+		CompletionEndpoint:                 EndpointCompletion,
+		TokenizeEndpoint:                   EndpointCompletion,
+		ImageGenerationAsyncEndpoint:       EndpointImage,
+		OperationsEndpoint + "/op1":        EndpointOperations,
+		conversationsBaseURL + "/c1/items": EndpointConversations,
+	}
+	for u, want := range tests {
+		if got := endpointOf(u); got != want {
+			t.Errorf("endpointOf(%q) = %s, want %s", u, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package yandexgpt
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+const (
+	TextClassificationEndpoint        = "https://llm.api.cloud.yandex.net/foundationModels/v1/textClassification"
+	FewShotTextClassificationEndpoint = "https://llm.api.cloud.yandex.net/foundationModels/v1/fewShotTextClassification"
+)
+
+// ClassifyText assigns labels to text using the YandexGPT classifier. The
+// task description tells the model what the labels mean. Without samples the
+// classification is zero-shot; samples turn it into few-shot classification.
+// Predictions are sorted by confidence, highest first.
+//
+// See https://yandex.cloud/ru/docs/ai-studio/concepts/classifier/
+func (c *Client) ClassifyText(taskDescription string, labels []string, text string, samples []ClassificationSample, opts ...CallOption) (*ClassificationResponse, error) {
+	return c.ClassifyTextContext(context.Background(), taskDescription, labels, text, samples, opts...)
+}
+
+// ClassifyTextContext is like ClassifyText but aborts the request when ctx is done.
+func (c *Client) ClassifyTextContext(ctx context.Context, taskDescription string, labels []string, text string, samples []ClassificationSample, opts ...CallOption) (*ClassificationResponse, error) {
+	if len(labels) < 2 {
+		return nil, newInvalidArgumentError("at least two labels are required", nil)
+	}
+	if text == "" {
+		return nil, newInvalidArgumentError("text to classify cannot be empty", nil)
+	}
+
+	call := c.newCallOptions(opts)
+	request := FewShotClassificationRequest{
+		ModelURI:        models.GetClassifierModelURI(call.folderID),
+		TaskDescription: taskDescription,
+		Labels:          labels,
+		Text:            text,
+		Samples:         samples,
+	}
+
+	return c.sendClassificationRequest(ctx, CallFewShotClassification, FewShotTextClassificationEndpoint, call, &request)
+}
+
+// ClassifyWithTunedModel classifies text with a fine-tuned classifier. model
+// is either the ID of the tuned model or its full "cls://" URI. Predictions
+// are sorted by confidence, highest first.
+func (c *Client) ClassifyWithTunedModel(model, text string, opts ...CallOption) (*ClassificationResponse, error) {
+	return c.ClassifyWithTunedModelContext(context.Background(), model, text, opts...)
+}
+
+// ClassifyWithTunedModelContext is like ClassifyWithTunedModel but aborts the
+// request when ctx is done.
+func (c *Client) ClassifyWithTunedModelContext(ctx context.Context, model, text string, opts ...CallOption) (*ClassificationResponse, error) {
+	if model == "" {
+		return nil, newInvalidArgumentError("classifier model cannot be empty", nil)
+	}
+	if text == "" {
+		return nil, newInvalidArgumentError("text to classify cannot be empty", nil)
+	}
+
+	call := c.newCallOptions(opts)
+	request := TextClassificationRequest{
+		ModelURI: models.GetTunedClassifierModelURI(model, call.folderID),
+		Text:     text,
+	}
+
+	return c.sendClassificationRequest(ctx, CallTextClassification, TextClassificationEndpoint, call, &request)
+}
+
+func (c *Client) sendClassificationRequest(ctx context.Context, kind CallKind, endpoint string, call *callOptions, request interface{}) (*ClassificationResponse, error) {
+	return coalesce(ctx, c.flights, kind, call, request, func(ctx context.Context) (*ClassificationResponse, error) {
+		var response ClassificationResponse
+		if err := c.doRequest(ctx, call, kind, "POST", endpoint, request, &response); err != nil {
+			return nil, err
+		}
+
+		sort.SliceStable(response.Predictions, func(i, j int) bool {
+			return response.Predictions[i].Confidence > response.Predictions[j].Confidence
+		})
+
+		return &response, nil
+	})
+}
+
+// Top returns the prediction with the highest confidence. It reports false
+// when there are no predictions.
+func (r *ClassificationResponse) Top() (ClassificationPrediction, bool) {
+	if len(r.Predictions) == 0 {
+		return ClassificationPrediction{}, false
+	}
+	return r.Predictions[0], true
+}
@@ -0,0 +1,92 @@
+package yandexgpt
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestClassifyText(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foundationModels/v1/fewShotTextClassification" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+
+		var req FewShotClassificationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ModelURI != "cls://test_folder/yandexgpt/latest" {
+			t.Errorf("Unexpected model URI %s", req.ModelURI)
+		}
+		if len(req.Samples) != 1 || req.Samples[0].Label != "billing" {
+			t.Errorf("Expected one billing sample, got %+v", req.Samples)
+		}
+
+		json.NewEncoder(w).Encode(ClassificationResponse{
+			Predictions: []ClassificationPrediction{
+				{Label: "billing", Confidence: 0.1},
+				{Label: "bug", Confidence: 0.85},
+				{Label: "other", Confidence: 0.05},
+			},
+		})
+	})
+
+	response, err := client.ClassifyText(
+		"Determine the topic of a support ticket",
+		[]string{"billing", "bug", "other"},
+		"The app crashes on start",
+		[]ClassificationSample{{Text: "I was charged twice", Label: "billing"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, ok := response.Top()
+	if !ok || top.Label != "bug" {
+		t.Errorf("Expected top label bug, got %+v", top)
+	}
+	for i := 1; i < len(response.Predictions); i++ {
+		if response.Predictions[i-1].Confidence < response.Predictions[i].Confidence {
+			t.Errorf("Predictions are not sorted: %+v", response.Predictions)
+		}
+	}
+}
+
+func TestClassifyWithTunedModel(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foundationModels/v1/textClassification" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+
+		var req TextClassificationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ModelURI != "cls://test_folder/bt1tuned" {
+			t.Errorf("Unexpected model URI %s", req.ModelURI)
+		}
+
+		json.NewEncoder(w).Encode(ClassificationResponse{
+			Predictions: []ClassificationPrediction{{Label: "spam", Confidence: 0.99}},
+		})
+	})
+
+	response, err := client.ClassifyWithTunedModel("bt1tuned", "Buy now!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top, _ := response.Top(); top.Label != "spam" {
+		t.Errorf("Expected spam, got %s", top.Label)
+	}
+}
+
+func TestClassifyTextValidation(t *testing.T) {
+	client, _ := NewClient("test_token", "test_folder")
+
+	if _, err := client.ClassifyText("task", []string{"only"}, "text", nil); err == nil {
+		t.Error("Expected error for a single label")
+	}
+	if _, err := client.ClassifyText("task", []string{"a", "b"}, "", nil); err == nil {
+		t.Error("Expected error for empty text")
+	}
+	if _, ok := (&ClassificationResponse{}).Top(); ok {
+		t.Error("Expected no top prediction for empty response")
+	}
+}
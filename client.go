@@ -2,13 +2,17 @@ package yandexgpt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -17,16 +21,36 @@ const (
 	OperationsEndpoint           = "https://operation.api.cloud.yandex.net/operations"
 )
 
+// Client is safe for concurrent use and does not change after construction;
+// use WithFolder, WithCredentials, WithHTTPClient and WithOptions to derive
+// clients with different settings.
 type Client struct {
-	httpClient          *http.Client
-	oauthToken          string
-	folderID            string
-	iamToken            string
-	tokenExpiry         time.Time
-	conversationsClient *ConversationsClient
+	httpClient         *http.Client
+	credentials        Credentials
+	folderID           string
+	defaultCallOptions []CallOption
+	conversations      *ConversationsClient
+	hedging            *hedgeState
+	breakers           *circuitBreakers
+	interceptors       []Interceptor
+	tracerProvider     trace.TracerProvider
+	propagator         propagation.TextMapPropagator
+	hooks              Hooks
+	logger             *clientLogger
+	usage              *UsageTracker
+	budgets            budgets
+	cache              *responseCache
+	semanticCache      *SemanticCache
+	flights            *flightGroup
 }
 
-func NewClient(oauthToken, folderID string) (*Client, error) {
+func NewClient(oauthToken, folderID string, opts ...ClientOption) (*Client, error) {
+	return NewClientWithHTTPClient(oauthToken, folderID, &http.Client{
+		Timeout: 30 * time.Second,
+	}, opts...)
+}
+
+func NewClientWithHTTPClient(oauthToken, folderID string, httpClient *http.Client, opts ...ClientOption) (*Client, error) {
 	if oauthToken == "" {
 		return nil, NewAuthenticationError("OAuth token cannot be empty", nil)
 	}
@@ -34,91 +58,139 @@ func NewClient(oauthToken, folderID string) (*Client, error) {
 		return nil, NewAuthenticationError("Folder ID cannot be empty", nil)
 	}
 
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		oauthToken: oauthToken,
-		folderID:   folderID,
-	}, nil
+	return newClient(NewOAuthCredentials(oauthToken, httpClient), folderID, httpClient, opts), nil
 }
 
-func NewClientWithHTTPClient(oauthToken, folderID string, httpClient *http.Client) (*Client, error) {
-	if oauthToken == "" {
-		return nil, NewAuthenticationError("OAuth token cannot be empty", nil)
+// NewClientWithCredentials creates a client authorized by credentials, such
+// as an IAM token or an API key.
+func NewClientWithCredentials(credentials Credentials, folderID string, opts ...ClientOption) (*Client, error) {
+	if credentials == nil {
+		return nil, NewAuthenticationError("credentials cannot be nil", nil)
 	}
 	if folderID == "" {
 		return nil, NewAuthenticationError("Folder ID cannot be empty", nil)
 	}
 
-	return &Client{
-		httpClient: httpClient,
-		oauthToken: oauthToken,
-		folderID:   folderID,
-	}, nil
+	return newClient(credentials, folderID, &http.Client{Timeout: 30 * time.Second}, opts), nil
 }
 
-func (c *Client) getValidIAMToken() (string, error) {
-	if c.iamToken != "" && time.Now().Before(c.tokenExpiry) {
-		return c.iamToken, nil
+func newClient(credentials Credentials, folderID string, httpClient *http.Client, opts []ClientOption) *Client {
+	c := &Client{
+		httpClient:  httpClient,
+		credentials: credentials,
+		folderID:    folderID,
+		hedging:     newHedgeState(),
 	}
-
-	type iamRequest struct {
-		YandexPassportOauthToken string `json:"yandexPassportOauthToken"`
+	for _, opt := range opts {
+		opt(c)
 	}
-
-	type iamResponse struct {
-		IAMToken  string    `json:"iamToken"`
-		ExpiresAt time.Time `json:"expiresAt"`
+	if c.breakers != nil {
+		c.breakers.policy.OnStateChange = chainHooks(c.breakers.policy.OnStateChange, c.hooks.OnCircuitStateChange)
 	}
+	c.conversations = &ConversationsClient{client: c}
+	return c
+}
 
-	reqBody, err := json.Marshal(iamRequest{
-		YandexPassportOauthToken: c.oauthToken,
-	})
-	if err != nil {
-		return "", NewAuthenticationError("failed to marshal IAM request", err)
-	}
+// derive returns a copy of c to be adjusted by a With* method.
+func (c *Client) derive(adjust func(*Client)) *Client {
+	d := &Client{
+		httpClient:         c.httpClient,
+		credentials:        c.credentials,
+		folderID:           c.folderID,
+		defaultCallOptions: append([]CallOption(nil), c.defaultCallOptions...),
+		hedging:            c.hedging,
+		breakers:           c.breakers,
+		interceptors:       c.interceptors,
+		tracerProvider:     c.tracerProvider,
+		propagator:         c.propagator,
+		hooks:              c.hooks,
+		logger:             c.logger,
+		usage:              c.usage,
+		budgets:            c.budgets,
+		cache:              c.cache,
+		semanticCache:      c.semanticCache,
+		flights:            c.flights,
+	}
+	adjust(d)
+	d.conversations = &ConversationsClient{client: d}
+	return d
+}
 
-	req, err := http.NewRequest("POST", "https://iam.api.cloud.yandex.net/iam/v1/tokens", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", NewAuthenticationError("failed to create IAM request", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
+// WithFolder returns a client for another folder. It shares the credentials,
+// and so the cached IAM token, and the HTTP client with c.
+func (c *Client) WithFolder(folderID string) *Client {
+	return c.derive(func(d *Client) { d.folderID = folderID })
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", NewAuthenticationError("failed to get IAM token", err)
-	}
-	defer resp.Body.Close()
+// WithCredentials returns a client authorized by other credentials. It
+// shares the folder, options and HTTP client with c.
+func (c *Client) WithCredentials(credentials Credentials) *Client {
+	return c.derive(func(d *Client) { d.credentials = credentials })
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", NewAuthenticationError(fmt.Sprintf("IAM token request failed with status %d: %s", resp.StatusCode, string(body)), nil)
-	}
+// WithHTTPClient returns a client sending API requests with httpClient. The
+// credentials and their cached IAM token are shared with c; an IAM token
+// exchange caused by a call of the returned client uses httpClient too.
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	return c.derive(func(d *Client) { d.httpClient = httpClient })
+}
+
+// WithOptions returns a client that applies opts to every call, after the
+// default call options of c.
+func (c *Client) WithOptions(opts ...CallOption) *Client {
+	return c.derive(func(d *Client) { d.defaultCallOptions = append(d.defaultCallOptions, opts...) })
+}
 
-	var iamResp iamResponse
-	if err := json.NewDecoder(resp.Body).Decode(&iamResp); err != nil {
-		return "", NewAuthenticationError("failed to decode IAM response", err)
+func (c *Client) GenerateText(prompt, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return c.GenerateTextContext(context.Background(), prompt, model, options, opts...)
+}
+
+// GenerateTextContext is like GenerateText but aborts the request when ctx is done.
+func (c *Client) GenerateTextContext(ctx context.Context, prompt, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	messages := []Message{
+		{
+			Role: "user",
+			Text: prompt,
+		},
 	}
 
-	c.iamToken = iamResp.IAMToken
-	c.tokenExpiry = iamResp.ExpiresAt.Add(-5 * time.Minute)
+	return c.complete(ctx, messages, model, options, opts)
+}
 
-	return c.iamToken, nil
+func (c *Client) GenerateFromMessages(messages []Message, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return c.GenerateFromMessagesContext(context.Background(), messages, model, options, opts...)
 }
 
-func (c *Client) GenerateText(prompt, model string, options *CompletionOptions) (*CompletionResponse, error) {
-	if !models.IsValidModel(model) {
-		return nil, NewAPIError(fmt.Sprintf("invalid model: %s", model), 0, nil)
-	}
+// GenerateFromMessagesContext is like GenerateFromMessages but aborts the
+// request when ctx is done.
+func (c *Client) GenerateFromMessagesContext(ctx context.Context, messages []Message, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return c.complete(ctx, messages, model, options, opts)
+}
 
-	iamToken, err := c.getValidIAMToken()
+// GenerateTextRef is like GenerateTextContext but takes the model as a
+// models.ModelRef, which is validated before the request is sent.
+func (c *Client) GenerateTextRef(ctx context.Context, prompt string, model models.ModelRef, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	name, err := refModel(model)
 	if err != nil {
 		return nil, err
 	}
+	return c.GenerateTextContext(ctx, prompt, name, options, opts...)
+}
 
-	modelURI := models.GetModelURI(model, c.folderID)
+// GenerateFromMessagesRef is like GenerateFromMessagesContext but takes the
+// model as a models.ModelRef, which is validated before the request is sent.
+func (c *Client) GenerateFromMessagesRef(ctx context.Context, messages []Message, model models.ModelRef, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	name, err := refModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenerateFromMessagesContext(ctx, messages, name, options, opts...)
+}
 
+// complete sends a completion request to model and, if it fails with an
+// error accepted by the call's fallback policy, to the fallback models in
+// turn. Fallback models that reject the options are skipped.
+func (c *Client) complete(ctx context.Context, messages []Message, model string, options *CompletionOptions, opts []CallOption) (*CompletionResponse, error) {
 	if options == nil {
 		options = &CompletionOptions{
 			Stream:      false,
@@ -127,204 +199,427 @@ func (c *Client) GenerateText(prompt, model string, options *CompletionOptions)
 		}
 	}
 
-	request := CompletionRequest{
-		ModelURI:          modelURI,
-		CompletionOptions: *options,
-		Messages: []Message{
-			{
-				Role: "user",
-				Text: prompt,
-			},
-		},
+	call := c.newCallOptions(opts)
+	chain := append([]string{model}, call.fallback.chain(model)...)
+
+	if call.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, call.timeout)
+		defer cancel()
 	}
 
-	return c.sendCompletionRequest(iamToken, request)
-}
+	var lastErr error
+	for i, m := range chain {
+		request, err := c.completionRequest(call, messages, m, options)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			continue
+		}
+
+		response, err := c.sendCompletionRequest(ctx, call, request)
+		if err == nil {
+			if metadata := response.ResponseMetadata(); metadata != nil {
+				metadata.Model = m
+			}
+			return response, nil
+		}
 
-func (c *Client) GenerateFromMessages(messages []Message, model string, options *CompletionOptions) (*CompletionResponse, error) {
-	if !models.IsValidModel(model) {
-		return nil, NewAPIError(fmt.Sprintf("invalid model: %s", model), 0, nil)
+		if metadata := ErrorMetadata(err); metadata != nil {
+			metadata.Model = m
+		}
+		lastErr = err
+		if ctx.Err() != nil || !call.fallback.shouldFallback(err) {
+			return nil, err
+		}
 	}
+	return nil, lastErr
+}
 
-	iamToken, err := c.getValidIAMToken()
+func (c *Client) completionRequest(call *callOptions, messages []Message, model string, options *CompletionOptions) (CompletionRequest, error) {
+	ref, err := c.parseModel(model)
 	if err != nil {
-		return nil, err
+		return CompletionRequest{}, err
 	}
 
-	modelURI := models.GetModelURI(model, c.folderID)
-
-	if options == nil {
-		options = &CompletionOptions{
-			Stream:      false,
-			Temperature: 0.6,
-			MaxTokens:   2000,
-		}
+	if err := validateCompletionOptions(ref, options); err != nil {
+		return CompletionRequest{}, err
 	}
 
-	request := CompletionRequest{
-		ModelURI:          modelURI,
+	return CompletionRequest{
+		ModelURI:          ref.URI(call.folderID),
 		CompletionOptions: *options,
 		Messages:          messages,
-	}
-
-	return c.sendCompletionRequest(iamToken, request)
+	}, nil
 }
 
-func (c *Client) sendCompletionRequest(iamToken string, request CompletionRequest) (*CompletionResponse, error) {
-	reqBody, err := json.Marshal(request)
+// parseModel accepts any well-formed model reference, see models.ModelRef.
+func (c *Client) parseModel(model string) (models.ModelRef, error) {
+	ref, err := models.ParseModelRef(model)
 	if err != nil {
-		return nil, NewAPIError("failed to marshal request", 0, err)
+		return models.ModelRef{}, newInvalidArgumentError(fmt.Sprintf("invalid model: %s", model), err)
+	}
+	return ref, nil
+}
+
+// refModel returns ref in the string form taken by the other methods.
+func refModel(ref models.ModelRef) (string, error) {
+	if err := ref.Validate(); err != nil {
+		return "", newInvalidArgumentError(fmt.Sprintf("invalid model: %s", ref), err)
 	}
+	return ref.String(), nil
+}
 
-	req, err := http.NewRequest("POST", CompletionEndpoint, bytes.NewBuffer(reqBody))
+func (c *Client) resolveModelURI(model, folderID string) (string, error) {
+	ref, err := c.parseModel(model)
 	if err != nil {
-		return nil, NewAPIError("failed to create request", 0, err)
+		return "", err
 	}
+	return ref.URI(folderID), nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+iamToken)
-	req.Header.Set("Content-Type", "application/json")
+// validateCompletionOptions rejects options the model is known not to
+// support. Models missing from models.DefaultRegistry are not checked.
+func validateCompletionOptions(ref models.ModelRef, options *CompletionOptions) error {
+	info, ok := lookupModelInfo(ref)
+	if !ok {
+		return nil
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, NewAPIError("failed to send request", 0, err)
+	if r := options.ReasoningOptions; r != nil && r.Mode != "" && r.Mode != "DISABLED" && !info.SupportsReasoning {
+		return newInvalidArgumentError(fmt.Sprintf("model %s does not support reasoning options", info.Name), nil)
 	}
-	defer resp.Body.Close()
+	if info.MaxOutputTokens > 0 && options.MaxTokens > info.MaxOutputTokens {
+		return newInvalidArgumentError(fmt.Sprintf("maxTokens %d exceeds the limit of %d for model %s", options.MaxTokens, info.MaxOutputTokens, info.Name), nil)
+	}
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, NewAPIError("failed to read response", resp.StatusCode, err)
+func lookupModelInfo(ref models.ModelRef) (models.ModelInfo, bool) {
+	if ref.Scheme == models.SchemeDataSphere {
+		return models.ModelInfo{}, false
 	}
+	return models.Lookup(ref.Name)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResp map[string]interface{}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			if msg, ok := errorResp["message"].(string); ok {
-				return nil, NewAPIError(msg, resp.StatusCode, nil)
-			}
+func (c *Client) sendCompletionRequest(ctx context.Context, call *callOptions, request CompletionRequest) (*CompletionResponse, error) {
+	model := requestModel(&request)
+	send := func(ctx context.Context) (*CompletionResponse, error) {
+		request := request
+		var response CompletionResponse
+		if err := c.doRequest(ctx, call, CallCompletion, "POST", CompletionEndpoint, &request, &response); err != nil {
+			return nil, err
 		}
-		return nil, NewAPIError(fmt.Sprintf("API request failed: %s", string(body)), resp.StatusCode, nil)
+		if metadata := response.ResponseMetadata(); metadata != nil {
+			c.hedging.observe(model, metadata.Latency)
+		}
+		return &response, nil
 	}
 
-	var response CompletionResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, NewAPIError("failed to decode response", resp.StatusCode, err)
+	if call.hedge != nil {
+		unhedged := send
+		send = func(ctx context.Context) (*CompletionResponse, error) {
+			return c.hedge(ctx, model, call.hedge, unhedged)
+		}
 	}
 
-	return &response, nil
-}
-
-func (c *Client) GenerateImageAsync(messages interface{}, options *GenerationOptions, catalogID *string) (*Operation, error) {
-	iamToken, err := c.getValidIAMToken()
+	response, err := coalesce(ctx, c.flights, CallCompletion, call, &request, func(ctx context.Context) (*CompletionResponse, error) {
+		return c.cachedCompletion(ctx, call, &request, send)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	folderID := c.folderID
-	if catalogID != nil {
-		folderID = *catalogID
+	if err := contentFilterError(response); err != nil {
+		return nil, err
 	}
 
-	modelURI := models.GetARTModelURI(folderID)
+	return response, nil
+}
 
-	if options == nil {
-		options = &GenerationOptions{}
+// contentFilterError reports a response in which the content filter blocked
+// every alternative.
+func contentFilterError(response *CompletionResponse) error {
+	alternatives := response.Result.Alternatives
+	if len(alternatives) == 0 {
+		return nil
+	}
+	for _, alt := range alternatives {
+		if alt.Status != AlternativeStatusContentFilter {
+			return nil
+		}
+	}
+	apiErr := NewAPIError("the response was blocked by the content filter", http.StatusOK, nil)
+	if metadata := response.ResponseMetadata(); metadata != nil {
+		apiErr.Metadata = metadata
+		apiErr.RequestID = metadata.RequestID
 	}
+	return &ContentFilteredError{APIError: apiErr, Response: response}
+}
 
-	artMessages := normalizeARTMessages(messages)
+// doRequest sends an authenticated JSON request with the call options applied
+// and decodes the response into result, retrying as the call's retry policy
+// allows. Failed responses are converted with NewErrorFromResponse.
+func (c *Client) doRequest(ctx context.Context, call *callOptions, kind CallKind, method, requestURL string, body, result interface{}) (err error) {
+	if call.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, call.timeout)
+		defer cancel()
+	}
+
+	ctx, span := c.startSpan(ctx, kind, method, requestURL, body)
+	c.logger.request(ctx, kind, method, requestURL, call.header, body)
+	start := time.Now()
+	defer func() {
+		endSpan(span, result, err)
+		c.logger.response(ctx, kind, body, result, time.Since(start), err)
+	}()
+
+	if len(c.interceptors) == 0 {
+		return c.invoke(ctx, call, kind, method, requestURL, body, result)
+	}
+
+	inv := &Invocation{
+		Kind:     kind,
+		Model:    requestModel(body),
+		Method:   method,
+		URL:      requestURL,
+		Request:  body,
+		Response: result,
+		Header:   call.header.Clone(),
+	}
+	return c.intercept(ctx, inv, func(ctx context.Context, inv *Invocation) error {
+		intercepted := *call
+		intercepted.header = inv.Header
+		return c.invoke(ctx, &intercepted, inv.Kind, inv.Method, inv.URL, inv.Request, inv.Response)
+	})
+}
 
-	request := ImageGenerationRequest{
-		ModelURI:          modelURI,
-		GenerationOptions: *options,
-		Messages:          artMessages,
+// invoke sends a request, retrying it according to the call's retry policy,
+// and decodes the response into result.
+func (c *Client) invoke(ctx context.Context, call *callOptions, kind CallKind, method, requestURL string, body, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return NewAPIError("failed to marshal request", 0, err)
+		}
 	}
 
-	reqBody, err := json.Marshal(request)
+	reservation, err := c.budgets.reserve(call, body)
 	if err != nil {
-		return nil, NewAPIError("failed to marshal request", 0, err)
+		return err
 	}
+	defer reservation.release()
 
-	req, err := http.NewRequest("POST", ImageGenerationAsyncEndpoint, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, NewAPIError("failed to create request", 0, err)
+	clientID := call.header.Get(HeaderClientRequestID)
+	if clientID == "" {
+		clientID = clientRequestID(ctx)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+iamToken)
-	req.Header.Set("Content-Type", "application/json")
+	for attempt := 1; ; attempt++ {
+		metadata, err := c.sendAttempt(ctx, call, clientID, method, requestURL, payload, result)
+		if metadata != nil {
+			metadata.Attempts = attempt
+			metadata.Tags = call.tags
+		}
+		if err == nil {
+			if setter, ok := result.(metadataSetter); ok {
+				setter.setMetadata(metadata)
+			}
+			c.usage.record(call, kind, body, result)
+			reservation.settle(body, result)
+			if c.hooks.OnAttempt != nil {
+				c.hooks.OnAttempt(AttemptEvent{Kind: kind, Attempt: attempt, Metadata: metadata})
+			}
+			return nil
+		}
+		if metadata != nil {
+			err = withErrorMetadata(err, metadata)
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, NewAPIError("failed to send request", 0, err)
+		retry := call.retry.shouldRetry(err, attempt)
+		var backoff time.Duration
+		if retry {
+			backoff = call.retry.backoff(attempt, err)
+		}
+		if c.hooks.OnAttempt != nil {
+			c.hooks.OnAttempt(AttemptEvent{Kind: kind, Attempt: attempt, Err: err, Metadata: metadata, Retry: retry, Backoff: backoff})
+		}
+
+		if !retry {
+			return err
+		}
+		c.logger.retry(ctx, kind, attempt, backoff, err)
+		if sleepContext(ctx, backoff) != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := io.ReadAll(resp.Body)
+// sendAttempt makes a single HTTP request. The returned metadata is nil when
+// the request could not be sent at all.
+func (c *Client) sendAttempt(ctx context.Context, call *callOptions, clientID, method, requestURL string, payload []byte, result interface{}) (*ResponseMetadata, error) {
+	circuit, err := c.breakers.allow(endpointOf(requestURL))
 	if err != nil {
-		return nil, NewAPIError("failed to read response", resp.StatusCode, err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResp map[string]interface{}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			if msg, ok := errorResp["message"].(string); ok {
-				return nil, NewAPIError(msg, resp.StatusCode, nil)
-			}
-		}
-		return nil, NewAPIError(fmt.Sprintf("API request failed: %s", string(body)), resp.StatusCode, nil)
+	authorization, err := c.authorize(ctx)
+	if err != nil {
+		circuit.release()
+		return nil, err
 	}
 
-	var operation Operation
-	if err := json.Unmarshal(body, &operation); err != nil {
-		return nil, NewAPIError("failed to decode response", resp.StatusCode, err)
-	}
+	metadata, err := c.roundTrip(ctx, call, clientID, method, requestURL, payload, result, authorization)
+	circuit.record(ctx, err)
+	return metadata, err
+}
 
-	return &operation, nil
+// authorize returns the Authorization header. IAM token exchanges it
+// causes are guarded by the IAM circuit.
+func (c *Client) authorize(ctx context.Context) (string, error) {
+	onRefresh := c.hooks.OnIAMRefresh
+	if c.logger != nil {
+		onRefresh = chainHooks(onRefresh, c.logger.iamRefresh)
+	}
+
+	return c.credentials.Authorization(withIAMExchange(ctx, &iamExchange{
+		tracer:     c.tracer(),
+		propagator: c.textMapPropagator(),
+		httpClient: c.httpClient,
+		breakers:   c.breakers,
+		onRefresh:  onRefresh,
+	}))
 }
 
-func (c *Client) GetOperation(operationID string) (*Operation, error) {
-	iamToken, err := c.getValidIAMToken()
-	if err != nil {
-		return nil, err
+func (c *Client) roundTrip(ctx context.Context, call *callOptions, clientID, method, requestURL string, payload []byte, result interface{}, authorization string) (*ResponseMetadata, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", OperationsEndpoint, operationID), nil)
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reqBody)
 	if err != nil {
 		return nil, NewAPIError("failed to create request", 0, err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+iamToken)
 	req.Header.Set("Content-Type", "application/json")
+	for key, values := range call.header {
+		req.Header[key] = values
+	}
+	req.Header.Set(HeaderClientRequestID, clientID)
+	if call.idempotencyKey != "" {
+		req.Header.Set(HeaderIdempotencyKey, call.idempotencyKey)
+	}
+	req.Header.Set("Authorization", authorization)
+	c.textMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, NewAPIError("failed to send request", 0, err)
+		return newResponseMetadata(clientID, nil, time.Since(start)), NewAPIError("failed to send request", 0, err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
+	metadata := newResponseMetadata(clientID, resp, time.Since(start))
 	if err != nil {
-		return nil, NewAPIError("failed to read response", resp.StatusCode, err)
+		return metadata, NewAPIError("failed to read response", resp.StatusCode, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errorResp map[string]interface{}
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			if msg, ok := errorResp["message"].(string); ok {
-				return nil, NewAPIError(msg, resp.StatusCode, nil)
-			}
+		return metadata, NewErrorFromResponse(resp.StatusCode, resp.Header, respBody)
+	}
+
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return metadata, NewAPIError("failed to decode response", resp.StatusCode, err)
+	}
+
+	return metadata, nil
+}
+
+// withErrorMetadata attaches metadata to the APIError in err's chain.
+func withErrorMetadata(err error, metadata *ResponseMetadata) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Metadata = metadata
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = metadata.RequestID
 		}
-		return nil, NewAPIError(fmt.Sprintf("API request failed: %s", string(body)), resp.StatusCode, nil)
+	}
+	return err
+}
+
+func (c *Client) GenerateImageAsync(messages interface{}, options *GenerationOptions, catalogID *string, opts ...CallOption) (*Operation, error) {
+	return c.generateImageAsync(context.Background(), c.newCallOptions(opts), messages, options, catalogID)
+}
+
+func (c *Client) generateImageAsync(ctx context.Context, call *callOptions, messages interface{}, options *GenerationOptions, catalogID *string) (*Operation, error) {
+	folderID := call.folderID
+	if catalogID != nil {
+		folderID = *catalogID
+	}
+
+	modelURI := models.GetARTModelURI(folderID)
+
+	if options == nil {
+		options = &GenerationOptions{}
+	}
+
+	artMessages := normalizeARTMessages(messages)
+
+	request := ImageGenerationRequest{
+		ModelURI:          modelURI,
+		GenerationOptions: *options,
+		Messages:          artMessages,
 	}
 
 	var operation Operation
-	if err := json.Unmarshal(body, &operation); err != nil {
-		return nil, NewAPIError("failed to decode response", resp.StatusCode, err)
+	if err := c.doRequest(ctx, call, CallImageGeneration, "POST", ImageGenerationAsyncEndpoint, &request, &operation); err != nil {
+		return nil, err
 	}
 
 	return &operation, nil
 }
 
-func (c *Client) GenerateImage(messages interface{}, options *GenerationOptions, catalogID *string) (*ImageGenerationResult, error) {
-	operation, err := c.GenerateImageAsync(messages, options, catalogID)
+func (c *Client) GetOperation(operationID string, opts ...CallOption) (*Operation, error) {
+	return c.getOperation(context.Background(), c.newCallOptions(opts), operationID)
+}
+
+func (c *Client) getOperation(ctx context.Context, call *callOptions, operationID string) (*Operation, error) {
+	var operation Operation
+	if err := c.doRequest(ctx, call, CallGetOperation, "GET", fmt.Sprintf("%s/%s", OperationsEndpoint, operationID), nil, &operation); err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+func (c *Client) GenerateImage(messages interface{}, options *GenerationOptions, catalogID *string, opts ...CallOption) (*ImageGenerationResult, error) {
+	call := c.newCallOptions(opts)
+
+	ctx := context.Background()
+	if call.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, call.timeout)
+		defer cancel()
+	}
+
+	ctx, span := c.tracer().Start(ctx, "GenerateImage", trace.WithAttributes(
+		attrGenAISystem.String(genAISystem),
+		attrGenAIOperation.String(genAIOperation(CallImageGeneration)),
+	))
+	result, err := c.generateImage(ctx, call, messages, options, catalogID)
+	endSpan(span, result, err)
+	return result, err
+}
+
+// generateImage starts an image generation and polls its operation until it
+// is done.
+func (c *Client) generateImage(ctx context.Context, call *callOptions, messages interface{}, options *GenerationOptions, catalogID *string) (*ImageGenerationResult, error) {
+	operation, err := c.generateImageAsync(ctx, call, messages, options, catalogID)
 	if err != nil {
 		return nil, err
 	}
@@ -333,36 +628,45 @@ func (c *Client) GenerateImage(messages interface{}, options *GenerationOptions,
 		return nil, NewAPIError("operation ID not found in response", 0, nil)
 	}
 
-	pollInterval := 2 * time.Second
+	pollInterval := call.pollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
 	maxWait := 10 * time.Minute
 	elapsed := time.Duration(0)
 
-	for elapsed < maxWait {
-		time.Sleep(pollInterval)
+	// An operation that is done when it starts is not polled.
+	op := operation
+	for iteration := 1; !op.Done; iteration++ {
+		if elapsed >= maxWait {
+			return nil, NewAPIError("operation timed out", 0, nil)
+		}
+		if err := sleepContext(ctx, pollInterval); err != nil {
+			return nil, NewAPIError("operation timed out", 0, err)
+		}
 		elapsed += pollInterval
 
-		op, err := c.GetOperation(operation.ID)
+		op, err = c.getOperation(ctx, call, operation.ID)
 		if err != nil {
 			return nil, err
 		}
+		c.logger.poll(ctx, operation.ID, iteration, elapsed, op.Done)
+	}
 
-		if op.Done {
-			if op.Error != nil {
-				return nil, NewAPIError(fmt.Sprintf("operation error: %s", op.Error.Message), op.Error.Code, nil)
-			}
-
-			if op.Response == nil || op.Response.Image == "" {
-				return nil, NewAPIError("image data not found in operation response", 0, nil)
-			}
+	if op.Error != nil {
+		return nil, newOperationError(op.Error)
+	}
 
-			return &ImageGenerationResult{
-				OperationID: operation.ID,
-				ImageBase64: op.Response.Image,
-			}, nil
-		}
+	if op.Response == nil || op.Response.Image == "" {
+		return nil, NewAPIError("image data not found in operation response", 0, nil)
 	}
 
-	return nil, NewAPIError("operation timed out", 0, nil)
+	result := &ImageGenerationResult{
+		OperationID: operation.ID,
+		ImageBase64: op.Response.Image,
+	}
+	result.setMetadata(op.ResponseMetadata())
+	return result, nil
 }
 
 func (c *Client) GetAvailableModels() []string {
@@ -373,18 +677,30 @@ func (c *Client) GetModelDescriptions() map[string]string {
 	return models.GetModelDescriptions()
 }
 
+// GetModelInfo returns the limits, capabilities and prices of model from
+// models.DefaultRegistry. Branches and fine-tuned variants report the
+// information of their base model.
+func (c *Client) GetModelInfo(model string) (models.ModelInfo, bool) {
+	ref, err := models.ParseModelRef(model)
+	if err != nil {
+		return models.ModelInfo{}, false
+	}
+	return lookupModelInfo(ref)
+}
+
 // Conversations returns the ConversationsClient for managing conversations and their items.
 func (c *Client) Conversations() *ConversationsClient {
-	if c.conversationsClient == nil {
-		c.conversationsClient = &ConversationsClient{client: c}
-	}
-	return c.conversationsClient
+	return c.conversations
 }
 
 func (c *Client) GetFolderID() string {
 	return c.folderID
 }
 
+// SetFolderID changes the folder of c. It must not be called while c is in use.
+//
+// Deprecated: use WithFolder to derive a client for another folder, or the
+// WithFolder call option for a single call.
 func (c *Client) SetFolderID(folderID string) {
 	c.folderID = folderID
 }
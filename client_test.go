@@ -1,7 +1,18 @@
 package yandexgpt
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/tigusigalpa/yandexgpt-go/v2/models"
 )
@@ -150,3 +161,347 @@ func TestNormalizeARTMessages(t *testing.T) {
 		})
 	}
 }
+
+// rewriteTransport sends every request to the test server, keeping the path,
+// so the production endpoint constants can be exercised as is.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// setupTestServer returns a client configured with opts whose requests,
+// including IAM token exchange, are served by handler.
+func setupTestServer(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) *Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/iam/v1/tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"iamToken":  "test_iam_token",
+			"expiresAt": "2100-01-01T00:00:00Z",
+		})
+	})
+	mux.HandleFunc("/", handler)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	client, err := NewClientWithHTTPClient("test_oauth_token", "test_folder", &http.Client{
+		Transport: rewriteTransport{target: target},
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestGenerateTextContext(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ModelURI != "gpt://test_folder/yandexgpt-lite" {
+			t.Errorf("Unexpected model URI %s", req.ModelURI)
+		}
+
+		json.NewEncoder(w).Encode(CompletionResponse{Result: Result{
+			Alternatives: []Alternative{{Message: Message{Role: "assistant", Text: "Привет!"}}},
+		}})
+	})
+
+	response, err := client.GenerateTextContext(context.Background(), "Hello", models.YandexGPTLite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Result.Alternatives[0].Message.Text != "Привет!" {
+		t.Errorf("Unexpected response %+v", response.Result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GenerateTextContext(ctx, "Hello", models.YandexGPTLite, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestGenerateTextModelRef(t *testing.T) {
+	var modelURI string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		modelURI = req.ModelURI
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+
+	tests := []struct {
+		model    string
+		expected string
+	}{
+		{"yandexgpt/rc", "gpt://test_folder/yandexgpt/rc"},
+		{models.Model(models.YandexGPTLite).WithBranch("latest").WithTuning("tamr1").String(), "gpt://test_folder/yandexgpt-lite/latest@tamr1"},
+		{"gpt://other_folder/yandexgpt", "gpt://other_folder/yandexgpt"},
+		{"ds://bt1deployment", "ds://bt1deployment"},
+	}
+
+	for _, tt := range tests {
+		if _, err := client.GenerateText("Hello", tt.model, nil); err != nil {
+			t.Fatalf("GenerateText with %s: %v", tt.model, err)
+		}
+		if modelURI != tt.expected {
+			t.Errorf("Model %s sent as %s, expected %s", tt.model, modelURI, tt.expected)
+		}
+	}
+
+	if _, err := client.GenerateText("Hello", "yandexgpt/rc/extra", nil); err == nil {
+		t.Error("Expected error for malformed model")
+	}
+
+	ref := models.Model(models.YandexGPT).WithBranch(models.BranchRC).InFolder("other_folder")
+	if _, err := client.GenerateTextRef(context.Background(), "Hello", ref, nil); err != nil || modelURI != "gpt://other_folder/yandexgpt/rc" {
+		t.Errorf("Unexpected model URI %s, %v", modelURI, err)
+	}
+	messages := []Message{{Role: "user", Text: "Hello"}}
+	if _, err := client.GenerateFromMessagesRef(context.Background(), messages, models.DataSphere("bt1deployment"), nil); err != nil || modelURI != "ds://bt1deployment" {
+		t.Errorf("Unexpected model URI %s, %v", modelURI, err)
+	}
+	// "yandexgpt/rc" as a name would be sent as a branch if not validated.
+	if _, err := client.GenerateTextRef(context.Background(), "Hello", models.Model("yandexgpt/rc"), nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected an invalid argument error for a malformed ref, got %v", err)
+	}
+}
+
+func TestGenerateTextValidatesModelCapabilities(t *testing.T) {
+	client, _ := NewClient("test_token", "test_folder")
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, &CompletionOptions{
+		MaxTokens:        100,
+		ReasoningOptions: &ReasoningOptions{Mode: "ENABLED_HIDDEN"},
+	})
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected reasoning options to be rejected for yandexgpt-lite, got %v", err)
+	}
+
+	_, err = client.GenerateText("Hello", models.YandexGPT, &CompletionOptions{MaxTokens: 1 << 20})
+	if err == nil {
+		t.Error("Expected maxTokens above the model limit to be rejected")
+	}
+}
+
+func TestGetModelInfo(t *testing.T) {
+	client, _ := NewClient("test_token", "test_folder")
+
+	info, ok := client.GetModelInfo("yandexgpt/rc")
+	if !ok || info.Name != models.YandexGPT || !info.SupportsReasoning {
+		t.Errorf("Expected yandexgpt info for its rc branch, got %+v", info)
+	}
+	if _, ok := client.GetModelInfo("ds://bt1deployment"); ok {
+		t.Error("Expected no info for a DataSphere model")
+	}
+}
+
+func TestGenerateTextAPIError(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-request-id", "req-42")
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"grpcCode":8,"httpCode":429,"message":"rate limit exceeded","httpStatus":"Too Many Requests"}}`))
+	})
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected RateLimitError, got %T: %v", err, err)
+	}
+	if rateErr.RequestID != "req-42" || rateErr.Message != "rate limit exceeded" {
+		t.Errorf("Unexpected error fields: %+v", rateErr.APIError)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("Expected errors.Is(err, ErrRateLimited)")
+	}
+}
+
+func TestGenerateTextContentFiltered(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(CompletionResponse{Result: Result{
+			Alternatives: []Alternative{{
+				Message: Message{Role: "assistant", Text: "Я не могу обсуждать эту тему."},
+				Status:  AlternativeStatusContentFilter,
+			}},
+			Usage: Usage{InputTextTokens: 5, TotalTokens: 12},
+		}})
+	})
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+
+	var filtered *ContentFilteredError
+	if !errors.As(err, &filtered) {
+		t.Fatalf("Expected ContentFilteredError, got %T: %v", err, err)
+	}
+	if filtered.Response == nil || filtered.Response.Result.Usage.TotalTokens != 12 {
+		t.Errorf("Expected the filtered response to be attached, got %+v", filtered.Response)
+	}
+}
+
+func TestClientDerivation(t *testing.T) {
+	var iamCalls int32
+	var modelURIs []string
+	var mu sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/iam/v1/tokens", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&iamCalls, 1)
+		json.NewEncoder(w).Encode(map[string]string{
+			"iamToken":  "test_iam_token",
+			"expiresAt": "2100-01-01T00:00:00Z",
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		modelURIs = append(modelURIs, req.ModelURI)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	base, err := NewClientWithHTTPClient("test_oauth_token", "folder_a", &http.Client{
+		Transport: rewriteTransport{target: target},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	derived := base.WithFolder("folder_b")
+	if base.GetFolderID() != "folder_a" || derived.GetFolderID() != "folder_b" {
+		t.Fatalf("Unexpected folders: base %s, derived %s", base.GetFolderID(), derived.GetFolderID())
+	}
+	if derived.Conversations() == base.Conversations() {
+		t.Error("Expected derived client to have its own ConversationsClient")
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range []*Client{base, derived, base, derived} {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			if _, err := c.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+				t.Error(err)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&iamCalls); n != 1 {
+		t.Errorf("Expected derived clients to share the IAM token cache, got %d IAM calls", n)
+	}
+	sort.Strings(modelURIs)
+	want := []string{
+		"gpt://folder_a/yandexgpt-lite", "gpt://folder_a/yandexgpt-lite",
+		"gpt://folder_b/yandexgpt-lite", "gpt://folder_b/yandexgpt-lite",
+	}
+	if !reflect.DeepEqual(modelURIs, want) {
+		t.Errorf("Expected %v, got %v", want, modelURIs)
+	}
+}
+
+func TestOAuthCredentialsSharedRefresh(t *testing.T) {
+	var iamCalls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&iamCalls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{
+			"iamToken":  "test_iam_token",
+			"expiresAt": "2100-01-01T00:00:00Z",
+		})
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	credentials := NewOAuthCredentials("test_oauth_token", &http.Client{Transport: rewriteTransport{target: target}})
+
+	// The caller that started the refresh leaves, and so does a caller
+	// whose deadline passes; neither waits for the exchange.
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := credentials.IAMToken(first)
+		firstErr <- err
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := credentials.IAMToken(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline of the caller, got %v", err)
+	}
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the first caller to be canceled, got %v", err)
+	}
+
+	// The refresh still completes for the callers that wait for it.
+	token := make(chan string, 1)
+	go func() {
+		iamToken, _ := credentials.IAMToken(context.Background())
+		token <- iamToken
+	}()
+	close(release)
+	if got := <-token; got != "test_iam_token" {
+		t.Errorf("Expected the shared refresh to deliver the token, got %q", got)
+	}
+	if n := atomic.LoadInt32(&iamCalls); n != 1 {
+		t.Errorf("Expected 1 IAM call, got %d", n)
+	}
+}
+
+func TestWithCredentials(t *testing.T) {
+	var authorization string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+
+	keyed := client.WithCredentials(APIKeyCredentials("secret-key"))
+	if _, err := keyed.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Api-Key secret-key" {
+		t.Errorf("Expected API key authorization, got %q", authorization)
+	}
+
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Bearer test_iam_token" {
+		t.Errorf("Expected the original client to keep its credentials, got %q", authorization)
+	}
+}
+
+func TestNewClientWithCredentials(t *testing.T) {
+	if _, err := NewClientWithCredentials(nil, "folder"); err == nil {
+		t.Error("Expected error for nil credentials")
+	}
+	if _, err := NewClientWithCredentials(IAMTokenCredentials("token"), ""); err == nil {
+		t.Error("Expected error for empty folder")
+	}
+
+	client, err := NewClientWithCredentials(IAMTokenCredentials("token"), "folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetFolderID() != "folder" || client.Conversations() == nil {
+		t.Error("Expected a usable client")
+	}
+}
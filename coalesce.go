@@ -0,0 +1,194 @@
+package yandexgpt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// WithRequestCoalescing makes concurrent identical completions and
+// classifications share one upstream call: a call whose request equals a
+// call in flight waits for its result instead of sending its own. Requests
+// are identical when their canonical JSON is, as for CacheKey, and so are
+// the headers of the calls, including WithHeader and WithIdempotencyKey
+// ones. The timeout, retry policy and tags of the first caller apply to the
+// shared call; calls that need their own use WithCoalescingBypass. The
+// responses of the other callers report ResponseMetadata.Coalesced.
+//
+// A caller whose context is done stops waiting without canceling the
+// shared call, which is canceled only once every caller has left. Derived
+// clients share the calls in flight.
+func WithRequestCoalescing() ClientOption {
+	return func(c *Client) {
+		c.flights = &flightGroup{calls: map[string]*flight{}}
+	}
+}
+
+// WithCoalescingBypass sends the call on its own even if an identical call
+// is in flight, and does not let later calls share it.
+func WithCoalescingBypass() CallOption {
+	return func(o *callOptions) {
+		o.noCoalescing = true
+	}
+}
+
+// flightGroup tracks the calls in flight by request key.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a shared call.
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	result  interface{}
+	err     error
+}
+
+// requestKey returns a hash of kind and the canonical JSON of request.
+func requestKey(kind CallKind, request interface{}) string {
+	// encoding/json writes struct fields in order and map keys sorted, so
+	// equal requests encode identically.
+	data, _ := json.Marshal(request)
+	sum := sha256.Sum256(data)
+	return string(kind) + ":" + hex.EncodeToString(sum[:])
+}
+
+// flightKey identifies the calls that send the same request: the request
+// key extended with the headers of the call.
+func flightKey(kind CallKind, call *callOptions, request interface{}) string {
+	return requestKey(kind, struct {
+		Header         http.Header
+		IdempotencyKey string
+		Request        interface{}
+	}{call.header, call.idempotencyKey, request})
+}
+
+// responsePointer is a pointer to a response type embedding withMetadata.
+type responsePointer[T any] interface {
+	*T
+	metadataSetter
+	ResponseMetadata() *ResponseMetadata
+}
+
+// coalesce runs fn, or waits for the call in flight with the same kind and
+// request. Every caller gets its own copy of the result or error, with
+// coalesced metadata for all but the one that started the call.
+func coalesce[T any, P responsePointer[T]](ctx context.Context, g *flightGroup, kind CallKind, call *callOptions, request interface{}, fn func(context.Context) (P, error)) (P, error) {
+	if g == nil || call.noCoalescing {
+		return fn(ctx)
+	}
+	key := flightKey(kind, call, request)
+
+	g.mu.Lock()
+	f, shared := g.calls[key]
+	if !shared {
+		// The call outlives the context of the caller that started it.
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = f
+		go func() {
+			f.result, f.err = fn(callCtx)
+			g.forget(key, f)
+			cancel()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, shareError(f.err, shared)
+		}
+		return shareResult(f.result.(P), shared), nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if g.calls[key] == f {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) forget(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+}
+
+// shareResult returns a deep copy of result, so callers never see each
+// other's modifications.
+func shareResult[T any, P responsePointer[T]](result P, coalesced bool) P {
+	shared := P(new(T))
+	if data, err := json.Marshal(result); err == nil {
+		json.Unmarshal(data, shared)
+	}
+	if m := result.ResponseMetadata(); m != nil {
+		shared.setMetadata(shareMetadata(m, coalesced))
+	}
+	return shared
+}
+
+// shareError returns a copy of err with its own ResponseMetadata, so callers
+// may annotate the error as they do results. Errors without metadata are
+// returned as is.
+func shareError(err error, coalesced bool) error {
+	switch e := err.(type) {
+	case *AuthenticationError:
+		shared := *e
+		shared.Metadata = shareMetadata(e.Metadata, coalesced)
+		return &shared
+	case *APIError:
+		return shareAPIError(e, coalesced)
+	case *RateLimitError:
+		shared := *e
+		shared.APIError = shareAPIError(e.APIError, coalesced)
+		return &shared
+	case *QuotaExceededError:
+		return &QuotaExceededError{APIError: shareAPIError(e.APIError, coalesced)}
+	case *PermissionDeniedError:
+		return &PermissionDeniedError{APIError: shareAPIError(e.APIError, coalesced)}
+	case *InvalidArgumentError:
+		return &InvalidArgumentError{APIError: shareAPIError(e.APIError, coalesced)}
+	case *NotFoundError:
+		return &NotFoundError{APIError: shareAPIError(e.APIError, coalesced)}
+	case *UnavailableError:
+		return &UnavailableError{APIError: shareAPIError(e.APIError, coalesced)}
+	case *ContentFilteredError:
+		shared := &ContentFilteredError{APIError: shareAPIError(e.APIError, coalesced)}
+		if e.Response != nil {
+			shared.Response = shareResult(e.Response, coalesced)
+		}
+		return shared
+	}
+	return err
+}
+
+func shareAPIError(e *APIError, coalesced bool) *APIError {
+	shared := *e
+	shared.Metadata = shareMetadata(e.Metadata, coalesced)
+	return &shared
+}
+
+func shareMetadata(m *ResponseMetadata, coalesced bool) *ResponseMetadata {
+	if m == nil {
+		return nil
+	}
+	metadata := *m
+	metadata.Coalesced = coalesced
+	return &metadata
+}
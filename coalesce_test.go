@@ -0,0 +1,280 @@
+package yandexgpt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+// waitForWaiters waits until n callers wait for the only call in flight.
+func waitForWaiters(t *testing.T, g *flightGroup, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		waiters := 0
+		for _, f := range g.calls {
+			waiters += f.waiters
+		}
+		g.mu.Unlock()
+		if waiters == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d waiters", n)
+}
+
+func TestRequestCoalescing(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		writeCompletion(w, "shared")
+	}, WithRequestCoalescing())
+
+	const callers = 5
+	responses := make([]*CompletionResponse, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			responses[i] = response
+		}(i)
+	}
+	waitForWaiters(t, client.flights, callers)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+	coalesced := 0
+	for _, response := range responses {
+		if response == nil || response.Result.Alternatives[0].Message.Text != "shared" {
+			t.Fatalf("Unexpected response %+v", response)
+		}
+		if response.ResponseMetadata().Coalesced {
+			coalesced++
+		}
+	}
+	if coalesced != callers-1 {
+		t.Errorf("Expected %d coalesced responses, got %d", callers-1, coalesced)
+	}
+
+	// Calls made after the shared call completed are sent again, and
+	// different requests are never coalesced.
+	client.GenerateText("Hello", models.YandexGPTLite, nil)
+	client.GenerateText("Bye", models.YandexGPTLite, nil)
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected 3 upstream calls, got %d", calls)
+	}
+}
+
+// blockingServer returns a client whose completions wait for release. The
+// server signals on started when a completion arrives and closes canceled
+// when the client abandons it.
+func blockingServer(t *testing.T, release chan struct{}) (client *Client, started, canceled chan struct{}) {
+	started, canceled = make(chan struct{}, 1), make(chan struct{})
+	client = setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// The server notices a closed connection once the body is read.
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		select {
+		case <-release:
+			writeCompletion(w, "shared")
+		case <-r.Context().Done():
+			close(canceled)
+		}
+	}, WithRequestCoalescing())
+	return client, started, canceled
+}
+
+func TestRequestCoalescingCancellation(t *testing.T) {
+	release := make(chan struct{})
+	client, _, _ := blockingServer(t, release)
+
+	// The caller that started the call leaves; the other one still gets
+	// the response.
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateTextContext(firstCtx, "Hello", models.YandexGPTLite, nil)
+		firstErr <- err
+	}()
+	waitForWaiters(t, client.flights, 1)
+
+	second := make(chan *CompletionResponse, 1)
+	go func() {
+		response, _ := client.GenerateTextContext(context.Background(), "Hello", models.YandexGPTLite, nil)
+		second <- response
+	}()
+	waitForWaiters(t, client.flights, 2)
+
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the first caller to be canceled, got %v", err)
+	}
+	close(release)
+	if response := <-second; response == nil || response.Result.Alternatives[0].Message.Text != "shared" {
+		t.Errorf("Expected the second caller to get the response, got %+v", response)
+	}
+}
+
+func TestRequestCoalescingAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	client, started, canceled := blockingServer(t, release)
+
+	// Once every caller has left, the shared call is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.GenerateTextContext(ctx, "Hello", models.YandexGPTLite, nil)
+		close(done)
+	}()
+	<-started
+	cancel()
+	<-done
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the upstream call to be canceled")
+	}
+}
+
+func TestClassificationCoalescing(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Write([]byte(`{"predictions":[{"label":"a","confidence":0.2},{"label":"b","confidence":0.8}]}`))
+	}, WithRequestCoalescing())
+
+	var wg sync.WaitGroup
+	tops := make([]string, 3)
+	for i := range tops {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := client.ClassifyText("Topic", []string{"a", "b"}, "text", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			top, _ := response.Top()
+			tops[i] = top.Label
+		}(i)
+	}
+	waitForWaiters(t, client.flights, len(tops))
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+	for _, top := range tops {
+		if top != "b" {
+			t.Errorf("Expected sorted predictions for every caller, got %v", tops)
+		}
+	}
+}
+
+func TestRequestCoalescingFailure(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}, WithRequestCoalescing(), WithDefaultCallOptions(WithRetryPolicy(RetryPolicy{})))
+
+	// Every caller annotates its own copy of the error with the model.
+	const callers = 5
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = client.GenerateText("Hello", models.YandexGPTLite, nil)
+		}(i)
+	}
+	waitForWaiters(t, client.flights, callers)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+	coalesced := 0
+	seen := map[*ResponseMetadata]bool{}
+	for _, err := range errs {
+		metadata := ErrorMetadata(err)
+		if !errors.Is(err, ErrUnavailable) || metadata == nil || metadata.Model != models.YandexGPTLite {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if seen[metadata] {
+			t.Error("Expected every caller to get its own metadata")
+		}
+		seen[metadata] = true
+		if metadata.Coalesced {
+			coalesced++
+		}
+	}
+	if coalesced != callers-1 {
+		t.Errorf("Expected %d coalesced errors, got %d", callers-1, coalesced)
+	}
+}
+
+func TestRequestCoalescingKey(t *testing.T) {
+	var calls int32
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		<-release
+		writeCompletion(w, "ok")
+	}, WithRequestCoalescing())
+
+	// Calls with other headers, and calls that bypass coalescing, are
+	// sent on their own.
+	var wg sync.WaitGroup
+	for _, opt := range []CallOption{WithHeader("X-Tenant", "a"), WithHeader("X-Tenant", "b"), WithCoalescingBypass()} {
+		wg.Add(1)
+		go func(opt CallOption) {
+			defer wg.Done()
+			if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil, opt); err != nil {
+				t.Error(err)
+			}
+		}(opt)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected 3 upstream calls")
+		}
+	}
+	close(release)
+	wg.Wait()
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected 3 upstream calls, got %d", calls)
+	}
+}
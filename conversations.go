@@ -1,11 +1,8 @@
 package yandexgpt
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"strconv"
 )
@@ -24,7 +21,7 @@ type ConversationsClient struct {
 // Create creates a new conversation with optional metadata and initial items.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/createConversation
-func (cc *ConversationsClient) Create(metadata map[string]string, items []ConversationItem) (*Conversation, error) {
+func (cc *ConversationsClient) Create(metadata map[string]string, items []ConversationItem, opts ...CallOption) (*Conversation, error) {
 	body := make(map[string]interface{})
 
 	if metadata != nil {
@@ -36,7 +33,7 @@ func (cc *ConversationsClient) Create(metadata map[string]string, items []Conver
 	}
 
 	var conversation Conversation
-	if err := cc.sendRequest("POST", conversationsBaseURL, body, &conversation); err != nil {
+	if err := cc.sendRequest(CallCreateConversation, "POST", conversationsBaseURL, body, &conversation, opts); err != nil {
 		return nil, err
 	}
 
@@ -46,9 +43,9 @@ func (cc *ConversationsClient) Create(metadata map[string]string, items []Conver
 // Get retrieves a conversation by its ID.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/getConversation
-func (cc *ConversationsClient) Get(conversationID string) (*Conversation, error) {
+func (cc *ConversationsClient) Get(conversationID string, opts ...CallOption) (*Conversation, error) {
 	var conversation Conversation
-	if err := cc.sendRequest("GET", fmt.Sprintf("%s/%s", conversationsBaseURL, conversationID), nil, &conversation); err != nil {
+	if err := cc.sendRequest(CallGetConversation, "GET", fmt.Sprintf("%s/%s", conversationsBaseURL, conversationID), nil, &conversation, opts); err != nil {
 		return nil, err
 	}
 
@@ -58,7 +55,7 @@ func (cc *ConversationsClient) Get(conversationID string) (*Conversation, error)
 // Update updates the metadata of a conversation.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/updateConversation
-func (cc *ConversationsClient) Update(conversationID string, metadata map[string]string) (*Conversation, error) {
+func (cc *ConversationsClient) Update(conversationID string, metadata map[string]string, opts ...CallOption) (*Conversation, error) {
 	body := make(map[string]interface{})
 
 	if metadata != nil {
@@ -66,7 +63,7 @@ func (cc *ConversationsClient) Update(conversationID string, metadata map[string
 	}
 
 	var conversation Conversation
-	if err := cc.sendRequest("POST", fmt.Sprintf("%s/%s", conversationsBaseURL, conversationID), body, &conversation); err != nil {
+	if err := cc.sendRequest(CallUpdateConversation, "POST", fmt.Sprintf("%s/%s", conversationsBaseURL, conversationID), body, &conversation, opts); err != nil {
 		return nil, err
 	}
 
@@ -76,9 +73,9 @@ func (cc *ConversationsClient) Update(conversationID string, metadata map[string
 // Delete deletes a conversation by its ID.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/deleteConversation
-func (cc *ConversationsClient) Delete(conversationID string) (*ConversationDeleted, error) {
+func (cc *ConversationsClient) Delete(conversationID string, opts ...CallOption) (*ConversationDeleted, error) {
 	var result ConversationDeleted
-	if err := cc.sendRequest("DELETE", fmt.Sprintf("%s/%s", conversationsBaseURL, conversationID), nil, &result); err != nil {
+	if err := cc.sendRequest(CallDeleteConversation, "DELETE", fmt.Sprintf("%s/%s", conversationsBaseURL, conversationID), nil, &result, opts); err != nil {
 		return nil, err
 	}
 
@@ -88,13 +85,13 @@ func (cc *ConversationsClient) Delete(conversationID string) (*ConversationDelet
 // CreateItems adds items to a conversation.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/createConversationItems
-func (cc *ConversationsClient) CreateItems(conversationID string, items []ConversationItem) (*ConversationItemsList, error) {
+func (cc *ConversationsClient) CreateItems(conversationID string, items []ConversationItem, opts ...CallOption) (*ConversationItemsList, error) {
 	body := map[string]interface{}{
 		"items": items,
 	}
 
 	var result ConversationItemsList
-	if err := cc.sendRequest("POST", fmt.Sprintf("%s/%s/items", conversationsBaseURL, conversationID), body, &result); err != nil {
+	if err := cc.sendRequest(CallCreateItems, "POST", fmt.Sprintf("%s/%s/items", conversationsBaseURL, conversationID), body, &result, opts); err != nil {
 		return nil, err
 	}
 
@@ -104,7 +101,7 @@ func (cc *ConversationsClient) CreateItems(conversationID string, items []Conver
 // ListItems retrieves items from a conversation with optional pagination parameters.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/listConversationItems
-func (cc *ConversationsClient) ListItems(conversationID string, opts *ListItemsOptions) (*ConversationItemsList, error) {
+func (cc *ConversationsClient) ListItems(conversationID string, opts *ListItemsOptions, callOpts ...CallOption) (*ConversationItemsList, error) {
 	u := fmt.Sprintf("%s/%s/items", conversationsBaseURL, conversationID)
 
 	if opts != nil {
@@ -124,7 +121,7 @@ func (cc *ConversationsClient) ListItems(conversationID string, opts *ListItemsO
 	}
 
 	var result ConversationItemsList
-	if err := cc.sendRequest("GET", u, nil, &result); err != nil {
+	if err := cc.sendRequest(CallListItems, "GET", u, nil, &result, callOpts); err != nil {
 		return nil, err
 	}
 
@@ -134,9 +131,9 @@ func (cc *ConversationsClient) ListItems(conversationID string, opts *ListItemsO
 // GetItem retrieves a single item from a conversation.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/getConversationItem
-func (cc *ConversationsClient) GetItem(conversationID, itemID string) (*ConversationItem, error) {
+func (cc *ConversationsClient) GetItem(conversationID, itemID string, opts ...CallOption) (*ConversationItem, error) {
 	var item ConversationItem
-	if err := cc.sendRequest("GET", fmt.Sprintf("%s/%s/items/%s", conversationsBaseURL, conversationID, itemID), nil, &item); err != nil {
+	if err := cc.sendRequest(CallGetItem, "GET", fmt.Sprintf("%s/%s/items/%s", conversationsBaseURL, conversationID, itemID), nil, &item, opts); err != nil {
 		return nil, err
 	}
 
@@ -146,62 +143,18 @@ func (cc *ConversationsClient) GetItem(conversationID, itemID string) (*Conversa
 // DeleteItem deletes an item from a conversation.
 //
 // See https://yandex.cloud/ru/docs/ai-studio/conversations/deleteConversationItem
-func (cc *ConversationsClient) DeleteItem(conversationID, itemID string) (*Conversation, error) {
+func (cc *ConversationsClient) DeleteItem(conversationID, itemID string, opts ...CallOption) (*Conversation, error) {
 	var conversation Conversation
-	if err := cc.sendRequest("DELETE", fmt.Sprintf("%s/%s/items/%s", conversationsBaseURL, conversationID, itemID), nil, &conversation); err != nil {
+	if err := cc.sendRequest(CallDeleteItem, "DELETE", fmt.Sprintf("%s/%s/items/%s", conversationsBaseURL, conversationID, itemID), nil, &conversation, opts); err != nil {
 		return nil, err
 	}
 
 	return &conversation, nil
 }
 
-func (cc *ConversationsClient) sendRequest(method, requestURL string, body interface{}, result interface{}) error {
-	iamToken, err := cc.client.getValidIAMToken()
-	if err != nil {
-		return err
+func (cc *ConversationsClient) sendRequest(kind CallKind, method, requestURL string, body interface{}, result interface{}, opts []CallOption) error {
+	if method != "POST" && method != "PUT" && method != "PATCH" {
+		body = nil
 	}
-
-	var reqBody io.Reader
-	if body != nil && (method == "POST" || method == "PUT" || method == "PATCH") {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
-			return NewAPIError("failed to marshal request", 0, err)
-		}
-		reqBody = bytes.NewBuffer(jsonBody)
-	}
-
-	req, err := http.NewRequest(method, requestURL, reqBody)
-	if err != nil {
-		return NewAPIError("failed to create request", 0, err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+iamToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := cc.client.httpClient.Do(req)
-	if err != nil {
-		return NewAPIError("failed to send request", 0, err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return NewAPIError("failed to read response", resp.StatusCode, err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		var errorResp map[string]interface{}
-		if err := json.Unmarshal(respBody, &errorResp); err == nil {
-			if msg, ok := errorResp["message"].(string); ok {
-				return NewAPIError(msg, resp.StatusCode, nil)
-			}
-		}
-		return NewAPIError(fmt.Sprintf("Conversations API request failed: %s", string(respBody)), resp.StatusCode, nil)
-	}
-
-	if err := json.Unmarshal(respBody, result); err != nil {
-		return NewAPIError("failed to decode response", resp.StatusCode, err)
-	}
-
-	return nil
+	return cc.client.doRequest(context.Background(), cc.client.newCallOptions(opts), kind, method, requestURL, body, result)
 }
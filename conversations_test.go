@@ -2,6 +2,7 @@ package yandexgpt
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,25 +11,14 @@ import (
 func setupConversationsTestServer(t *testing.T, handler http.HandlerFunc) (*Client, *httptest.Server) {
 	t.Helper()
 
-	iamCalled := false
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/iam/v1/tokens", func(w http.ResponseWriter, r *http.Request) {
-		iamCalled = true
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"iamToken": "test_iam_token"})
 	})
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if !iamCalled && r.URL.Path != "/iam/v1/tokens" {
-			// First call should be IAM token
-			iamCalled = true
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{"iamToken": "test_iam_token"})
-			return
-		}
-		handler(w, r)
-	})
+	mux.HandleFunc("/", handler)
 
 	server := httptest.NewServer(mux)
 
@@ -37,11 +27,8 @@ func setupConversationsTestServer(t *testing.T, handler http.HandlerFunc) (*Clie
 		t.Fatal(err)
 	}
 
-	// Pre-set IAM token to avoid IAM call complexity
-	client.iamToken = "test_iam_token"
-	client.tokenExpiry = client.tokenExpiry.Add(1<<63 - 1) // far future
-
-	return client, server
+	// Use a fixed IAM token to avoid IAM call complexity
+	return client.WithCredentials(IAMTokenCredentials("test_iam_token")), server
 }
 
 func TestConversationsCreate(t *testing.T) {
@@ -367,10 +354,16 @@ func TestConversationsAPIError(t *testing.T) {
 		t.Fatal("Expected error, got nil")
 	}
 
-	apiErr, ok := err.(*APIError)
-	if !ok {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
 		t.Fatalf("Expected APIError, got %T", err)
 	}
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument, got %v", err)
+	}
+	if apiErr.Message != "Bad Request: invalid conversation_id" {
+		t.Errorf("Unexpected message %q", apiErr.Message)
+	}
 	if apiErr.StatusCode != http.StatusBadRequest {
 		t.Errorf("Expected status 400, got %d", apiErr.StatusCode)
 	}
@@ -0,0 +1,247 @@
+package yandexgpt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IAMTokenEndpoint exchanges OAuth tokens for IAM tokens.
+const IAMTokenEndpoint = "https://iam.api.cloud.yandex.net/iam/v1/tokens"
+
+// Credentials authorize API requests. Implementations must be safe for
+// concurrent use.
+type Credentials interface {
+	// Authorization returns the value of the Authorization header.
+	Authorization(ctx context.Context) (string, error)
+}
+
+// OAuthCredentials exchange a Yandex Passport OAuth token for IAM tokens and
+// cache them until shortly before they expire. Clients derived from one
+// another share the credentials and therefore the cached token.
+type OAuthCredentials struct {
+	oauthToken string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	iamToken string
+	expiry   time.Time
+	// refreshing is the token refresh in flight, if any.
+	refreshing *iamRefresh
+}
+
+// iamRefresh is a token refresh shared by the callers that need a new IAM
+// token at the same time.
+type iamRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// iamRefreshTimeout bounds a token refresh, which no caller can cancel.
+const iamRefreshTimeout = 30 * time.Second
+
+// NewOAuthCredentials returns credentials for the given OAuth token. The IAM
+// token is requested with the HTTP client of the Client whose call needs it,
+// such as one set with WithHTTPClient, and otherwise with httpClient, or a
+// client with a 30 second timeout if httpClient is nil.
+func NewOAuthCredentials(oauthToken string, httpClient *http.Client) *OAuthCredentials {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &OAuthCredentials{oauthToken: oauthToken, httpClient: httpClient}
+}
+
+// Authorization implements Credentials.
+func (o *OAuthCredentials) Authorization(ctx context.Context) (string, error) {
+	token, err := o.IAMToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// IAMToken returns a valid IAM token, requesting a new one when the cached
+// token is about to expire. Concurrent callers share one refresh, which is
+// not canceled when they leave: a caller whose ctx is done returns ctx.Err()
+// without waiting for it.
+func (o *OAuthCredentials) IAMToken(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	if o.iamToken != "" && time.Now().Before(o.expiry) {
+		token := o.iamToken
+		o.mu.Unlock()
+		return token, nil
+	}
+	refresh := o.refreshing
+	if refresh == nil {
+		refresh = &iamRefresh{done: make(chan struct{})}
+		o.refreshing = refresh
+		go o.runRefresh(context.WithoutCancel(ctx), refresh)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-refresh.done:
+		return refresh.token, refresh.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// runRefresh requests a token for refresh with ctx, which carries the values
+// of the caller that started it, and caches the token.
+func (o *OAuthCredentials) runRefresh(ctx context.Context, refresh *iamRefresh) {
+	ctx, cancel := context.WithTimeout(ctx, iamRefreshTimeout)
+	defer cancel()
+
+	token, expiry, err := o.requestToken(ctx)
+
+	o.mu.Lock()
+	if err == nil {
+		o.iamToken, o.expiry = token, expiry
+	}
+	o.refreshing = nil
+	o.mu.Unlock()
+
+	refresh.token, refresh.err = token, err
+	close(refresh.done)
+}
+
+// requestToken exchanges the OAuth token for a new IAM token, guarded by the
+// IAM circuit and reported to the tracer and hooks of the client.
+func (o *OAuthCredentials) requestToken(ctx context.Context) (string, time.Time, error) {
+	x := iamExchangeFromContext(ctx)
+	circuit, err := x.breakers.allow(EndpointIAM)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	tracer := x.tracer
+	if tracer == nil {
+		tracer = tracerFromContext(ctx)
+	}
+	ctx, span := tracer.Start(ctx, "createIamToken", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrHTTPMethod.String("POST"), attrServerAddress.String("iam.api.cloud.yandex.net")))
+	start := time.Now()
+	token, expiry, err := o.refresh(ctx, x)
+	circuit.record(ctx, err)
+	endSpan(span, nil, err)
+	if x.onRefresh != nil {
+		x.onRefresh(IAMRefreshEvent{Latency: time.Since(start), Err: err})
+	}
+	return token, expiry, err
+}
+
+// refresh sends the token exchange request and returns the IAM token with
+// the time it should be replaced.
+func (o *OAuthCredentials) refresh(ctx context.Context, x *iamExchange) (string, time.Time, error) {
+	type iamRequest struct {
+		YandexPassportOauthToken string `json:"yandexPassportOauthToken"`
+	}
+
+	type iamResponse struct {
+		IAMToken  string    `json:"iamToken"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+
+	reqBody, err := json.Marshal(iamRequest{
+		YandexPassportOauthToken: o.oauthToken,
+	})
+	if err != nil {
+		return "", time.Time{}, NewAuthenticationError("failed to marshal IAM request", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", IAMTokenEndpoint, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return "", time.Time{}, NewAuthenticationError("failed to create IAM request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	propagator := x.propagator
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	httpClient := x.httpClient
+	if httpClient == nil {
+		httpClient = o.httpClient
+	}
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, NewAuthenticationError("failed to get IAM token", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		authErr := NewAuthenticationError(fmt.Sprintf("IAM token request failed with status %d: %s", resp.StatusCode, string(body)), nil)
+		authErr.Metadata = newResponseMetadata("", resp, time.Since(start))
+		return "", time.Time{}, authErr
+	}
+
+	var iamResp iamResponse
+	if err := json.NewDecoder(resp.Body).Decode(&iamResp); err != nil {
+		return "", time.Time{}, NewAuthenticationError("failed to decode IAM response", err)
+	}
+
+	return iamResp.IAMToken, iamResp.ExpiresAt.Add(-5 * time.Minute), nil
+}
+
+// iamExchange is the setup of the client whose call makes OAuth credentials
+// exchange their token. Credentials are shared by derived clients, which may
+// differ in tracing, hooks and HTTP client. Zero fields fall back to the
+// global OpenTelemetry setup and the HTTP client of the credentials.
+type iamExchange struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	httpClient *http.Client
+	breakers   *circuitBreakers
+	onRefresh  func(IAMRefreshEvent)
+}
+
+type iamExchangeKey struct{}
+
+// withIAMExchange makes OAuth credentials that refresh their IAM token with
+// ctx follow x.
+func withIAMExchange(ctx context.Context, x *iamExchange) context.Context {
+	return context.WithValue(ctx, iamExchangeKey{}, x)
+}
+
+func iamExchangeFromContext(ctx context.Context) *iamExchange {
+	if x, ok := ctx.Value(iamExchangeKey{}).(*iamExchange); ok {
+		return x
+	}
+	return &iamExchange{}
+}
+
+// IAMTokenCredentials authorize requests with an IAM token obtained
+// elsewhere, for example from the metadata service of a VM.
+type IAMTokenCredentials string
+
+// Authorization implements Credentials.
+func (t IAMTokenCredentials) Authorization(ctx context.Context) (string, error) {
+	if t == "" {
+		return "", NewAuthenticationError("IAM token cannot be empty", nil)
+	}
+	return "Bearer " + string(t), nil
+}
+
+// APIKeyCredentials authorize requests with a service account API key.
+type APIKeyCredentials string
+
+// Authorization implements Credentials.
+func (k APIKeyCredentials) Authorization(ctx context.Context) (string, error) {
+	if k == "" {
+		return "", NewAuthenticationError("API key cannot be empty", nil)
+	}
+	return "Api-Key " + string(k), nil
+}
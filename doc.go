@@ -108,7 +108,11 @@
 //
 // # Error Handling
 //
-// The package provides specific error types:
+// The package provides specific error types. API failures are parsed into
+// RateLimitError, QuotaExceededError, PermissionDeniedError,
+// InvalidArgumentError, NotFoundError, UnavailableError and
+// ContentFilteredError, all of which wrap *APIError and match the
+// corresponding Err* sentinel:
 //
 //	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
 //	if err != nil {
@@ -118,8 +122,10 @@
 //	    switch {
 //	    case errors.As(err, &authErr):
 //	        // Handle authentication error
+//	    case errors.Is(err, yandexgpt.ErrRateLimited):
+//	        // Back off and retry
 //	    case errors.As(err, &apiErr):
-//	        // Handle API error
+//	        // Handle API error, apiErr.RequestID identifies the request
 //	    default:
 //	        // Handle other errors
 //	    }
@@ -0,0 +1,40 @@
+package yandexgpt
+
+import (
+	"context"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+const TextEmbeddingEndpoint = "https://llm.api.cloud.yandex.net/foundationModels/v1/textEmbedding"
+
+// Embed returns the embedding vector of text. model is models.TextSearchDoc
+// for documents, models.TextSearchQuery for search queries, or the full
+// "emb://" URI of another embedding model.
+//
+// See https://yandex.cloud/ru/docs/ai-studio/concepts/embeddings
+func (c *Client) Embed(text, model string, opts ...CallOption) (*TextEmbeddingResponse, error) {
+	return c.EmbedContext(context.Background(), text, model, opts...)
+}
+
+// EmbedContext is like Embed but aborts the request when ctx is done.
+func (c *Client) EmbedContext(ctx context.Context, text, model string, opts ...CallOption) (*TextEmbeddingResponse, error) {
+	if text == "" {
+		return nil, newInvalidArgumentError("text to embed cannot be empty", nil)
+	}
+	if model == "" {
+		return nil, newInvalidArgumentError("embedding model cannot be empty", nil)
+	}
+
+	call := c.newCallOptions(opts)
+	request := TextEmbeddingRequest{
+		ModelURI: models.GetEmbeddingModelURI(model, call.folderID),
+		Text:     text,
+	}
+
+	var response TextEmbeddingResponse
+	if err := c.doRequest(ctx, call, CallTextEmbedding, "POST", TextEmbeddingEndpoint, &request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,41 @@
+package yandexgpt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestEmbed(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foundationModels/v1/textEmbedding" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var req TextEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ModelURI != "emb://test_folder/text-search-query/latest" || req.Text != "refunds" {
+			t.Errorf("Unexpected request %+v", req)
+		}
+		w.Write([]byte(`{"embedding":[0.5,-0.25],"numTokens":"3","modelVersion":"1"}`))
+	})
+
+	response, err := client.Embed("refunds", models.TextSearchQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Embedding) != 2 || response.Embedding[1] != -0.25 || response.NumTokens != "3" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	vector, err := client.Embedder(models.TextSearchQuery).Embed(context.Background(), "refunds")
+	if err != nil || len(vector) != 2 {
+		t.Errorf("Unexpected embedder result %v, %v", vector, err)
+	}
+
+	if _, err := client.Embed("", models.TextSearchQuery); err == nil {
+		t.Error("Expected an error for empty text")
+	}
+}
@@ -1,10 +1,20 @@
 package yandexgpt
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type YandexGPTError struct {
 	Message string
 	Err     error
+	// Metadata describes the HTTP exchange that failed, if a request was sent.
+	Metadata *ResponseMetadata
 }
 
 func (e *YandexGPTError) Error() string {
@@ -34,6 +44,15 @@ func NewAuthenticationError(message string, err error) *AuthenticationError {
 type APIError struct {
 	YandexGPTError
 	StatusCode int
+	// GRPCCode is the gRPC status code reported by the API, if any.
+	GRPCCode int
+	// HTTPStatus is the textual HTTP status reported in the error body.
+	HTTPStatus string
+	// Details holds the structured error details from the error body.
+	Details []map[string]interface{}
+	// RequestID is the x-request-id of the failed request, to be quoted to
+	// Yandex Cloud support.
+	RequestID string
 }
 
 func NewAPIError(message string, statusCode int, err error) *APIError {
@@ -52,3 +71,236 @@ func (e *APIError) Error() string {
 	}
 	return e.YandexGPTError.Error()
 }
+
+// Sentinel errors matched by the typed API errors below, for use with errors.Is:
+//
+//	if errors.Is(err, yandexgpt.ErrRateLimited) {
+//	    // back off
+//	}
+var (
+	ErrRateLimited      = errors.New("yandexgpt: rate limited")
+	ErrQuotaExceeded    = errors.New("yandexgpt: quota exceeded")
+	ErrPermissionDenied = errors.New("yandexgpt: permission denied")
+	ErrInvalidArgument  = errors.New("yandexgpt: invalid argument")
+	ErrNotFound         = errors.New("yandexgpt: not found")
+	ErrUnavailable      = errors.New("yandexgpt: service unavailable")
+	ErrContentFiltered  = errors.New("yandexgpt: content filtered")
+)
+
+// gRPC status codes reported in the grpcCode field of API errors.
+const (
+	grpcInvalidArgument    = 3
+	grpcDeadlineExceeded   = 4
+	grpcNotFound           = 5
+	grpcPermissionDenied   = 7
+	grpcResourceExhausted  = 8
+	grpcFailedPrecondition = 9
+	grpcOutOfRange         = 11
+	grpcInternal           = 13
+	grpcUnavailable        = 14
+	grpcUnauthenticated    = 16
+)
+
+// RateLimitError is returned when requests are sent faster than the folder's
+// rate quota allows (HTTP 429).
+type RateLimitError struct {
+	*APIError
+	// RetryAfter is the delay suggested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Unwrap() error        { return e.APIError }
+func (e *RateLimitError) Is(target error) bool { return target == ErrRateLimited }
+
+// QuotaExceededError is returned when a hard quota of the cloud, such as the
+// number of tokens per day, is exhausted.
+type QuotaExceededError struct {
+	*APIError
+}
+
+func (e *QuotaExceededError) Unwrap() error        { return e.APIError }
+func (e *QuotaExceededError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+// PermissionDeniedError is returned when the credentials are rejected or lack
+// access to the folder or model (HTTP 401 and 403).
+type PermissionDeniedError struct {
+	*APIError
+}
+
+func (e *PermissionDeniedError) Unwrap() error        { return e.APIError }
+func (e *PermissionDeniedError) Is(target error) bool { return target == ErrPermissionDenied }
+
+// InvalidArgumentError is returned when the request is malformed, either as
+// reported by the API (HTTP 400) or as detected by the client before sending.
+type InvalidArgumentError struct {
+	*APIError
+}
+
+func (e *InvalidArgumentError) Unwrap() error        { return e.APIError }
+func (e *InvalidArgumentError) Is(target error) bool { return target == ErrInvalidArgument }
+
+// NotFoundError is returned when the requested model, operation or
+// conversation does not exist (HTTP 404).
+type NotFoundError struct {
+	*APIError
+}
+
+func (e *NotFoundError) Unwrap() error        { return e.APIError }
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// UnavailableError is returned for transient server-side failures (HTTP 5xx).
+// The request may succeed if retried.
+type UnavailableError struct {
+	*APIError
+}
+
+func (e *UnavailableError) Unwrap() error        { return e.APIError }
+func (e *UnavailableError) Is(target error) bool { return target == ErrUnavailable }
+
+// ContentFilteredError is returned when the prompt or every generated
+// alternative was blocked by the content filter. For completions blocked
+// after generation, Response holds the response, including its usage.
+type ContentFilteredError struct {
+	*APIError
+	Response *CompletionResponse
+}
+
+func (e *ContentFilteredError) Unwrap() error        { return e.APIError }
+func (e *ContentFilteredError) Is(target error) bool { return target == ErrContentFiltered }
+
+// NewErrorFromResponse builds the most specific error for a failed API
+// response. It understands both the {"error": {...}} envelope with grpcCode,
+// httpCode, httpStatus and details, and flat {"code", "message"} bodies.
+func NewErrorFromResponse(statusCode int, header http.Header, body []byte) error {
+	payload := parseErrorBody(body)
+
+	message := payload.message
+	if message == "" {
+		message = fmt.Sprintf("API request failed: %s", string(body))
+	}
+
+	apiErr := NewAPIError(message, statusCode, nil)
+	apiErr.GRPCCode = payload.grpcCode
+	apiErr.HTTPStatus = payload.httpStatus
+	apiErr.Details = payload.details
+	apiErr.RequestID = header.Get("x-request-id")
+
+	return classifyAPIError(apiErr, header)
+}
+
+func classifyAPIError(apiErr *APIError, header http.Header) error {
+	if isContentFilterMessage(apiErr.Message) {
+		return &ContentFilteredError{APIError: apiErr}
+	}
+
+	switch {
+	case apiErr.GRPCCode == grpcResourceExhausted || apiErr.StatusCode == http.StatusTooManyRequests:
+		if isHardQuotaMessage(apiErr.Message) {
+			return &QuotaExceededError{APIError: apiErr}
+		}
+		return &RateLimitError{APIError: apiErr, RetryAfter: parseRetryAfter(header.Get("Retry-After"))}
+	case apiErr.GRPCCode == grpcPermissionDenied || apiErr.GRPCCode == grpcUnauthenticated ||
+		apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return &PermissionDeniedError{APIError: apiErr}
+	case apiErr.GRPCCode == grpcInvalidArgument || apiErr.GRPCCode == grpcFailedPrecondition || apiErr.GRPCCode == grpcOutOfRange ||
+		apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity:
+		return &InvalidArgumentError{APIError: apiErr}
+	case apiErr.GRPCCode == grpcNotFound || apiErr.StatusCode == http.StatusNotFound:
+		return &NotFoundError{APIError: apiErr}
+	case apiErr.GRPCCode == grpcUnavailable || apiErr.GRPCCode == grpcInternal || apiErr.GRPCCode == grpcDeadlineExceeded ||
+		apiErr.StatusCode >= http.StatusInternalServerError:
+		return &UnavailableError{APIError: apiErr}
+	}
+	return apiErr
+}
+
+// newInvalidArgumentError reports a request rejected by the client itself.
+func newInvalidArgumentError(message string, err error) *InvalidArgumentError {
+	return &InvalidArgumentError{APIError: NewAPIError(message, 0, err)}
+}
+
+// newOperationError converts the error of a finished long-running operation.
+func newOperationError(opErr *OperationError) error {
+	apiErr := NewAPIError(fmt.Sprintf("operation error: %s", opErr.Message), opErr.Code, nil)
+	apiErr.GRPCCode = opErr.Code
+	apiErr.Details = opErr.Details
+	return classifyAPIError(apiErr, http.Header{})
+}
+
+type errorPayload struct {
+	message    string
+	httpStatus string
+	grpcCode   int
+	details    []map[string]interface{}
+}
+
+func parseErrorBody(body []byte) errorPayload {
+	var payload errorPayload
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return payload
+	}
+
+	switch inner := raw["error"].(type) {
+	case map[string]interface{}:
+		raw = inner
+	case string:
+		payload.message = inner
+	}
+
+	if msg, ok := raw["message"].(string); ok {
+		payload.message = msg
+	}
+	payload.httpStatus, _ = raw["httpStatus"].(string)
+
+	if code, ok := raw["grpcCode"].(float64); ok {
+		payload.grpcCode = int(code)
+	} else if code, ok := raw["code"].(float64); ok {
+		payload.grpcCode = int(code)
+	}
+
+	if details, ok := raw["details"].([]interface{}); ok {
+		for _, d := range details {
+			if detail, ok := d.(map[string]interface{}); ok {
+				payload.details = append(payload.details, detail)
+			}
+		}
+	}
+
+	return payload
+}
+
+// isHardQuotaMessage tells exhausted periodic or billing quotas apart from
+// rate limits. Both are reported as RESOURCE_EXHAUSTED; rate limits usually
+// name a gauge such as "...SessionsCount.count gauge quota limit exceed".
+func isHardQuotaMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, marker := range []string{"per day", "per hour", "daily", "billing", "balance"} {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isContentFilterMessage(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "content filter") || strings.Contains(message, "content_filter") ||
+		strings.Contains(message, "inappropriate content")
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -2,7 +2,9 @@ package yandexgpt
 
 import (
 	"errors"
+	"net/http"
 	"testing"
+	"time"
 )
 
 func TestAuthenticationError(t *testing.T) {
@@ -73,3 +75,78 @@ func TestYandexGPTError(t *testing.T) {
 		t.Error("Expected Unwrap to return base error")
 	}
 }
+
+func TestNewErrorFromResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		sentinel error
+		target   interface{}
+	}{
+		{"rate limit", 429, `{"error":{"grpcCode":8,"httpCode":429,"message":"ai.textGenerationCompletionSessionsCount.count gauge quota limit exceed: allowed 10 requests","httpStatus":"Too Many Requests"}}`, ErrRateLimited, new(*RateLimitError)},
+		{"quota", 429, `{"error":{"grpcCode":8,"httpCode":429,"message":"quota exceeded for tokens per day","httpStatus":"Too Many Requests"}}`, ErrQuotaExceeded, new(*QuotaExceededError)},
+		{"permission", 403, `{"error":{"grpcCode":7,"httpCode":403,"message":"Permission denied","httpStatus":"Forbidden"}}`, ErrPermissionDenied, new(*PermissionDeniedError)},
+		{"unauthenticated", 401, `{"error":{"grpcCode":16,"httpCode":401,"message":"The token is invalid","httpStatus":"Unauthorized"}}`, ErrPermissionDenied, new(*PermissionDeniedError)},
+		{"invalid argument", 400, `{"error":{"grpcCode":3,"httpCode":400,"message":"Error in session","httpStatus":"Bad Request"}}`, ErrInvalidArgument, new(*InvalidArgumentError)},
+		{"flat body", 400, `{"code":3,"message":"invalid model uri"}`, ErrInvalidArgument, new(*InvalidArgumentError)},
+		{"not found", 404, `{"error":{"grpcCode":5,"httpCode":404,"message":"Operation not found","httpStatus":"Not Found"}}`, ErrNotFound, new(*NotFoundError)},
+		{"unavailable", 503, `upstream connect error`, ErrUnavailable, new(*UnavailableError)},
+		{"internal", 500, `{"error":{"grpcCode":13,"httpCode":500,"message":"Internal error","httpStatus":"Internal Server Error"}}`, ErrUnavailable, new(*UnavailableError)},
+		{"content filter", 400, `{"error":{"grpcCode":3,"httpCode":400,"message":"Request rejected by content filter","httpStatus":"Bad Request"}}`, ErrContentFiltered, new(*ContentFilteredError)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("x-request-id", "req-123")
+
+			err := NewErrorFromResponse(tt.status, header, []byte(tt.body))
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.sentinel)
+			}
+			if !errors.As(err, tt.target) {
+				t.Errorf("errors.As(%T) failed for %T", tt.target, err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected APIError in chain of %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if apiErr.RequestID != "req-123" {
+				t.Errorf("Expected request ID req-123, got %q", apiErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestNewErrorFromResponseDetails(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	body := `{"error":{"grpcCode":8,"httpCode":429,"message":"too many requests","httpStatus":"Too Many Requests","details":[{"@type":"type.googleapis.com/google.rpc.RequestInfo","requestId":"abc"}]}}`
+
+	err := NewErrorFromResponse(429, header, []byte(body))
+
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected RateLimitError, got %T", err)
+	}
+	if rateErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected RetryAfter 7s, got %v", rateErr.RetryAfter)
+	}
+	if rateErr.GRPCCode != 8 || rateErr.HTTPStatus != "Too Many Requests" {
+		t.Errorf("Unexpected codes: %d %q", rateErr.GRPCCode, rateErr.HTTPStatus)
+	}
+	if rateErr.Message != "too many requests" {
+		t.Errorf("Unexpected message %q", rateErr.Message)
+	}
+	if len(rateErr.Details) != 1 || rateErr.Details[0]["requestId"] != "abc" {
+		t.Errorf("Unexpected details %v", rateErr.Details)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("RateLimitError must not match ErrNotFound")
+	}
+}
@@ -0,0 +1,71 @@
+package yandexgpt
+
+import (
+	"errors"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+// FallbackPolicy lists the models a completion falls back to, in order, when
+// the requested model fails.
+type FallbackPolicy struct {
+	// Models are tried in order after the requested model.
+	Models []string
+	// On reports whether err moves the call to the next model. Defaults to
+	// IsFallbackError.
+	On func(err error) bool
+
+	defaults bool
+}
+
+// IsFallbackError reports whether err is worth answering with another model:
+// a rate limit, an exhausted quota or an unavailable model.
+func IsFallbackError(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrUnavailable)
+}
+
+func (p *FallbackPolicy) shouldFallback(err error) bool {
+	if p.On != nil {
+		return p.On(err)
+	}
+	return IsFallbackError(err)
+}
+
+// WithFallback makes a completion fall back to fallbackModels, in order, when
+// the requested model fails with an error accepted by IsFallbackError. The
+// model that served the call is reported in ResponseMetadata.Model:
+//
+//	response, err := client.GenerateText(prompt, models.YandexGPT, nil,
+//	    yandexgpt.WithFallback(models.YandexGPTLite))
+func WithFallback(fallbackModels ...string) CallOption {
+	return WithFallbackPolicy(FallbackPolicy{Models: fallbackModels})
+}
+
+// WithFallbackPolicy sets the fallback policy of a completion.
+func WithFallbackPolicy(policy FallbackPolicy) CallOption {
+	policy.Models = append([]string(nil), policy.Models...)
+	return func(o *callOptions) {
+		o.fallback = policy
+	}
+}
+
+// WithDefaultFallbacks falls back along models.Fallbacks of the requested
+// model. Unlike WithFallback it suits WithDefaultCallOptions, since the chain
+// depends on the model of each call.
+func WithDefaultFallbacks() CallOption {
+	return func(o *callOptions) {
+		o.fallback = FallbackPolicy{defaults: true}
+	}
+}
+
+// chain returns the fallback chain for the requested model.
+func (p *FallbackPolicy) chain(model string) []string {
+	if !p.defaults {
+		return p.Models
+	}
+	ref, err := models.ParseModelRef(model)
+	if err != nil || !ref.IsBuiltin() {
+		return nil
+	}
+	return models.Fallbacks(ref.Name)
+}
@@ -0,0 +1,130 @@
+package yandexgpt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+// setupFallbackTestServer fails requests to the models in failing with
+// status and records the model of every request.
+func setupFallbackTestServer(t *testing.T, status int, failing ...string) (*Client, *[]string) {
+	t.Helper()
+
+	var requested []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		model := strings.TrimPrefix(req.ModelURI, "gpt://test_folder/")
+		requested = append(requested, model)
+
+		for _, f := range failing {
+			if f == model {
+				w.WriteHeader(status)
+				w.Write([]byte(`{"error":{"message":"failed"}}`))
+				return
+			}
+		}
+		writeCompletion(w, model)
+	})
+	return client, &requested
+}
+
+func TestWithFallback(t *testing.T) {
+	client, requested := setupFallbackTestServer(t, http.StatusTooManyRequests, models.YandexGPT)
+
+	response, err := client.GenerateText("Hello", models.YandexGPT, nil, WithFallback(models.YandexGPTLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := response.ResponseMetadata().Model; got != models.YandexGPTLite {
+		t.Errorf("Expected the call to be served by %s, got %s", models.YandexGPTLite, got)
+	}
+	if want := []string{models.YandexGPT, models.YandexGPTLite}; strings.Join(*requested, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected requests to %v, got %v", want, *requested)
+	}
+}
+
+func TestFallbackNotTriggeredByPermanentErrors(t *testing.T) {
+	client, requested := setupFallbackTestServer(t, http.StatusBadRequest, models.YandexGPT)
+
+	_, err := client.GenerateText("Hello", models.YandexGPT, nil, WithFallback(models.YandexGPTLite))
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Expected ErrInvalidArgument, got %v", err)
+	}
+	if len(*requested) != 1 {
+		t.Errorf("Expected no fallback, got requests %v", *requested)
+	}
+	if metadata := ErrorMetadata(err); metadata == nil || metadata.Model != models.YandexGPT {
+		t.Errorf("Expected error metadata for %s, got %+v", models.YandexGPT, metadata)
+	}
+}
+
+func TestFallbackPolicyOn(t *testing.T) {
+	client, requested := setupFallbackTestServer(t, http.StatusBadRequest, models.YandexGPT)
+
+	_, err := client.GenerateText("Hello", models.YandexGPT, nil, WithFallbackPolicy(FallbackPolicy{
+		Models: []string{models.YandexGPTLite},
+		On:     func(err error) bool { return errors.Is(err, ErrInvalidArgument) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*requested) != 2 {
+		t.Errorf("Expected the custom policy to fall back, got requests %v", *requested)
+	}
+}
+
+func TestFallbackSkipsIncompatibleModels(t *testing.T) {
+	client, requested := setupFallbackTestServer(t, http.StatusServiceUnavailable, models.YandexGPT)
+
+	options := &CompletionOptions{
+		MaxTokens:        100,
+		ReasoningOptions: &ReasoningOptions{Mode: "ENABLED_HIDDEN"},
+	}
+	response, err := client.GenerateText("Hello", models.YandexGPT, options,
+		WithFallback(models.YandexGPTLite, models.AliceAI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := response.ResponseMetadata().Model; got != models.AliceAI {
+		t.Errorf("Expected %s without reasoning support to be skipped, got %s", models.YandexGPTLite, got)
+	}
+	if len(*requested) != 2 {
+		t.Errorf("Expected 2 requests, got %v", *requested)
+	}
+}
+
+func TestFallbackChainExhausted(t *testing.T) {
+	client, _ := setupFallbackTestServer(t, http.StatusServiceUnavailable, models.YandexGPT, models.YandexGPTLite)
+
+	_, err := client.GenerateText("Hello", models.YandexGPT, nil, WithFallback(models.YandexGPTLite))
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Expected ErrUnavailable, got %v", err)
+	}
+	if metadata := ErrorMetadata(err); metadata == nil || metadata.Model != models.YandexGPTLite {
+		t.Errorf("Expected the last model in the error metadata, got %+v", metadata)
+	}
+}
+
+func TestWithDefaultFallbacks(t *testing.T) {
+	client, requested := setupFallbackTestServer(t, http.StatusTooManyRequests, models.AliceAI, models.YandexGPT)
+	client = client.WithOptions(WithDefaultFallbacks())
+
+	response, err := client.GenerateText("Hello", models.AliceAI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.ResponseMetadata().Model; got != models.YandexGPTLite {
+		t.Errorf("Expected %s to serve the call, got %s", models.YandexGPTLite, got)
+	}
+	if len(*requested) != 3 {
+		t.Errorf("Expected 3 requests, got %v", *requested)
+	}
+}
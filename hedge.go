@@ -0,0 +1,203 @@
+package yandexgpt
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Hedging defaults used when the corresponding HedgePolicy field is zero.
+const (
+	DefaultHedgePercentile  = 0.95
+	DefaultHedgeDelay       = 2 * time.Second
+	DefaultHedgeBudgetRatio = 0.1
+	DefaultHedgeBudgetBurst = 10
+
+	// hedgeMinSamples is the number of observed latencies needed before the
+	// percentile delay replaces DefaultHedgeDelay.
+	hedgeMinSamples = 20
+	// hedgeWindow is the number of recent latencies the percentile is taken from.
+	hedgeWindow = 256
+)
+
+// HedgePolicy configures hedged completions: when the first request has not
+// answered after Delay, a duplicate is sent, the first response wins and the
+// other request is cancelled. Every hedge is paid for, so the budget limits
+// the extra traffic.
+type HedgePolicy struct {
+	// Delay before sending a hedge. When zero, the Percentile of recently
+	// observed completion latencies of the model with the client is used, or
+	// DefaultHedgeDelay until enough latencies of the model are known.
+	Delay time.Duration
+	// Percentile is a fraction such as 0.95. Defaults to DefaultHedgePercentile.
+	Percentile float64
+	// MaxHedges is the number of duplicates a call may send. Defaults to 1.
+	MaxHedges int
+	// BudgetRatio is the number of hedges earned by every hedged call, so
+	// 0.1 allows about 10% extra requests. Defaults to DefaultHedgeBudgetRatio.
+	BudgetRatio float64
+	// BudgetBurst caps the unused hedges that can accumulate. Defaults to
+	// DefaultHedgeBudgetBurst.
+	BudgetBurst float64
+}
+
+// WithHedging enables hedged requests for completions.
+func WithHedging(policy HedgePolicy) CallOption {
+	return func(o *callOptions) {
+		o.hedge = &policy
+	}
+}
+
+// hedgeState holds the latencies of every model and the hedge budget of a
+// client. Derived clients share it.
+type hedgeState struct {
+	mu        sync.Mutex
+	latencies map[string]*latencyWindow
+	tokens    float64
+	started   bool
+}
+
+// latencyWindow holds the recent latencies of one model.
+type latencyWindow struct {
+	latencies []time.Duration
+	next      int
+}
+
+func newHedgeState() *hedgeState {
+	return &hedgeState{latencies: map[string]*latencyWindow{}}
+}
+
+func (h *hedgeState) observe(model string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	w := h.latencies[model]
+	if w == nil {
+		w = &latencyWindow{latencies: make([]time.Duration, 0, hedgeWindow)}
+		h.latencies[model] = w
+	}
+	if len(w.latencies) < hedgeWindow {
+		w.latencies = append(w.latencies, latency)
+		return
+	}
+	w.latencies[w.next] = latency
+	w.next = (w.next + 1) % hedgeWindow
+}
+
+func (h *hedgeState) delay(model string, p *HedgePolicy) time.Duration {
+	if p.Delay > 0 {
+		return p.Delay
+	}
+
+	var sorted []time.Duration
+	h.mu.Lock()
+	if w := h.latencies[model]; w != nil {
+		sorted = append(sorted, w.latencies...)
+	}
+	h.mu.Unlock()
+
+	if len(sorted) < hedgeMinSamples {
+		return DefaultHedgeDelay
+	}
+
+	percentile := p.Percentile
+	if percentile <= 0 || percentile >= 1 {
+		percentile = DefaultHedgePercentile
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(percentile*float64(len(sorted)-1))]
+}
+
+// deposit credits the budget of a hedged call.
+func (h *hedgeState) deposit(p *HedgePolicy) {
+	ratio, burst := p.BudgetRatio, p.BudgetBurst
+	if ratio <= 0 {
+		ratio = DefaultHedgeBudgetRatio
+	}
+	if burst <= 0 {
+		burst = DefaultHedgeBudgetBurst
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.started {
+		h.tokens, h.started = burst, true
+	}
+	h.tokens += ratio
+	if h.tokens > burst {
+		h.tokens = burst
+	}
+}
+
+// take spends one hedge from the budget.
+func (h *hedgeState) take() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// hedge runs send and, while it has not answered, up to MaxHedges duplicates
+// spaced by the hedge delay of model. The first successful response wins and the
+// remaining requests are cancelled. If every request fails, the error of the
+// last one is returned.
+func (c *Client) hedge(ctx context.Context, model string, policy *HedgePolicy, send func(context.Context) (*CompletionResponse, error)) (*CompletionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	maxHedges := policy.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	c.hedging.deposit(policy)
+
+	type result struct {
+		response *CompletionResponse
+		err      error
+	}
+	results := make(chan result, maxHedges+1)
+	launch := func() {
+		go func() {
+			response, err := send(ctx)
+			results <- result{response, err}
+		}()
+	}
+
+	delay := c.hedging.delay(model, policy)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	launch()
+	inFlight, hedges := 1, 0
+
+	for {
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				if metadata := r.response.ResponseMetadata(); metadata != nil {
+					metadata.Hedges = hedges
+				}
+				return r.response, nil
+			}
+			if inFlight == 0 {
+				return nil, r.err
+			}
+		case <-timer.C:
+			if hedges < maxHedges && c.hedging.take() {
+				launch()
+				inFlight++
+				hedges++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
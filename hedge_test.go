@@ -0,0 +1,129 @@
+package yandexgpt
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestHedgedCompletion(t *testing.T) {
+	var calls int32
+	cancelled := make(chan struct{})
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// The first request stalls until the hedge wins and cancels it. The
+			// server only notices the cancellation once the body has been read.
+			io.Copy(io.Discard, r.Body)
+			<-r.Context().Done()
+			close(cancelled)
+			return
+		}
+		writeCompletion(w, "hedge")
+	})
+
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		WithHedging(HedgePolicy{Delay: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text := response.Result.Alternatives[0].Message.Text; text != "hedge" {
+		t.Errorf("Expected the hedge to win, got %q", text)
+	}
+	if hedges := response.ResponseMetadata().Hedges; hedges != 1 {
+		t.Errorf("Expected 1 hedge, got %d", hedges)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the stalled request to be cancelled")
+	}
+}
+
+func TestHedgingBudget(t *testing.T) {
+	var calls int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(30 * time.Millisecond)
+		writeCompletion(w, "ok")
+	})
+
+	policy := HedgePolicy{Delay: 5 * time.Millisecond, BudgetRatio: 0.01, BudgetBurst: 1}
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil, WithHedging(policy)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The burst allows one hedge; the second call earns only 0.01 more.
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("Expected 3 requests (2 calls + 1 hedge), got %d", n)
+	}
+}
+
+func TestHedgingReturnsEarlyError(t *testing.T) {
+	var calls int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"bad request"}}`))
+	})
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		WithHedging(HedgePolicy{Delay: time.Second}))
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Expected ErrInvalidArgument, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected no hedge after a fast failure, got %d requests", n)
+	}
+}
+
+func TestHedgeDelayPercentile(t *testing.T) {
+	h := newHedgeState()
+	policy := &HedgePolicy{Percentile: 0.95}
+
+	if d := h.delay("yandexgpt-lite", policy); d != DefaultHedgeDelay {
+		t.Errorf("Expected DefaultHedgeDelay without samples, got %v", d)
+	}
+
+	for i := 100; i >= 1; i-- {
+		h.observe("yandexgpt-lite", time.Duration(i)*time.Millisecond)
+	}
+	if d := h.delay("yandexgpt-lite", policy); d != 95*time.Millisecond {
+		t.Errorf("Expected p95 of 95ms, got %v", d)
+	}
+
+	if d := h.delay("yandexgpt-lite", &HedgePolicy{Delay: time.Second}); d != time.Second {
+		t.Errorf("Expected the fixed delay to win, got %v", d)
+	}
+}
+
+func TestHedgeDelayPerModel(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "ok")
+	})
+	for i := 0; i < hedgeMinSamples; i++ {
+		if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+			t.Fatal(err)
+		}
+		client.hedging.observe(models.YandexGPT, 10*time.Second)
+	}
+
+	policy := &HedgePolicy{}
+	if d := client.hedging.delay(models.YandexGPTLite, policy); d >= time.Second {
+		t.Errorf("Expected the fast model to hedge after its own latencies, got %v", d)
+	}
+	if d := client.hedging.delay(models.YandexGPT, policy); d != 10*time.Second {
+		t.Errorf("Expected the slow model to hedge after its own latencies, got %v", d)
+	}
+	if d := client.hedging.delay(models.AliceAI, policy); d != DefaultHedgeDelay {
+		t.Errorf("Expected DefaultHedgeDelay for a model without latencies, got %v", d)
+	}
+}
@@ -0,0 +1,72 @@
+package yandexgpt
+
+import "time"
+
+// Hooks are callbacks for client events that interceptors do not see
+// because they happen inside a call. Every field is optional. Hooks are
+// called synchronously, so they must be safe for concurrent use and must
+// not block.
+type Hooks struct {
+	// OnAttempt is called after every HTTP attempt of a call, including
+	// attempts that are retried.
+	OnAttempt func(AttemptEvent)
+	// OnIAMRefresh is called after OAuth credentials requested a new IAM
+	// token for the client.
+	OnIAMRefresh func(IAMRefreshEvent)
+	// OnCircuitStateChange is called after a state change of a circuit
+	// breaker of the client, in addition to CircuitBreakerPolicy.OnStateChange.
+	OnCircuitStateChange func(CircuitEvent)
+	// OnCacheLookup is called after a completion was looked up in the
+	// cache of WithCache.
+	OnCacheLookup func(CacheEvent)
+}
+
+// AttemptEvent describes an HTTP attempt of a call.
+type AttemptEvent struct {
+	Kind CallKind
+	// Attempt is 1 for the first attempt of a call.
+	Attempt int
+	// Err is the error of the attempt, or nil if it succeeded.
+	Err error
+	// Metadata describes the HTTP exchange, if a request was sent.
+	Metadata *ResponseMetadata
+	// Retry reports whether the call is retried after Backoff.
+	Retry   bool
+	Backoff time.Duration
+}
+
+// IAMRefreshEvent describes an exchange of an OAuth token for an IAM token.
+type IAMRefreshEvent struct {
+	Latency time.Duration
+	Err     error
+}
+
+// WithHooks adds hooks to the client. Hooks added by several options are
+// all called, in order. Clients derived from the client keep them.
+func WithHooks(hooks Hooks) ClientOption {
+	return func(c *Client) {
+		c.hooks = c.hooks.merge(hooks)
+	}
+}
+
+func (h Hooks) merge(other Hooks) Hooks {
+	return Hooks{
+		OnAttempt:            chainHooks(h.OnAttempt, other.OnAttempt),
+		OnIAMRefresh:         chainHooks(h.OnIAMRefresh, other.OnIAMRefresh),
+		OnCircuitStateChange: chainHooks(h.OnCircuitStateChange, other.OnCircuitStateChange),
+		OnCacheLookup:        chainHooks(h.OnCacheLookup, other.OnCacheLookup),
+	}
+}
+
+func chainHooks[E any](first, second func(E)) func(E) {
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+	return func(event E) {
+		first(event)
+		second(event)
+	}
+}
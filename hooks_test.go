@@ -0,0 +1,69 @@
+package yandexgpt
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestHooks(t *testing.T) {
+	var calls int32
+	var attempts []AttemptEvent
+	var refreshes []IAMRefreshEvent
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeCompletion(w, "ok")
+	},
+		WithHooks(Hooks{OnAttempt: func(e AttemptEvent) { attempts = append(attempts, e) }}),
+		WithHooks(Hooks{OnIAMRefresh: func(e IAMRefreshEvent) { refreshes = append(refreshes, e) }}),
+	)
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(attempts))
+	}
+	first, second := attempts[0], attempts[1]
+	if first.Kind != CallCompletion || first.Attempt != 1 || !first.Retry || first.Backoff != time.Millisecond || !errors.Is(first.Err, ErrUnavailable) {
+		t.Errorf("Unexpected first attempt: %+v", first)
+	}
+	if second.Attempt != 2 || second.Retry || second.Err != nil || second.Metadata == nil {
+		t.Errorf("Unexpected second attempt: %+v", second)
+	}
+
+	if len(refreshes) != 1 || refreshes[0].Err != nil {
+		t.Errorf("Expected one successful IAM refresh, got %+v", refreshes)
+	}
+}
+
+func TestCircuitHooks(t *testing.T) {
+	var fromPolicy, fromHooks []CircuitState
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	},
+		WithHooks(Hooks{OnCircuitStateChange: func(e CircuitEvent) { fromHooks = append(fromHooks, e.To) }}),
+		WithCircuitBreaker(CircuitBreakerPolicy{
+			ConsecutiveFailures: 1,
+			OnStateChange:       func(e CircuitEvent) { fromPolicy = append(fromPolicy, e.To) },
+		}),
+	)
+
+	client.GenerateText("Hello", models.YandexGPTLite, nil)
+
+	if len(fromPolicy) != 1 || len(fromHooks) != 1 || fromHooks[0] != CircuitOpen {
+		t.Errorf("Expected the opening to reach the policy and the hooks, got %v and %v", fromPolicy, fromHooks)
+	}
+}
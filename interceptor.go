@@ -0,0 +1,88 @@
+package yandexgpt
+
+import (
+	"context"
+	"net/http"
+)
+
+// CallKind names the API method of an Invocation.
+type CallKind string
+
+const (
+	CallCompletion            CallKind = "completion"
+	CallTokenize              CallKind = "tokenize"
+	CallTextEmbedding         CallKind = "textEmbedding"
+	CallTextClassification    CallKind = "textClassification"
+	CallFewShotClassification CallKind = "fewShotTextClassification"
+	CallImageGeneration       CallKind = "imageGenerationAsync"
+	CallGetOperation          CallKind = "getOperation"
+	CallCreateConversation    CallKind = "createConversation"
+	CallGetConversation       CallKind = "getConversation"
+	CallUpdateConversation    CallKind = "updateConversation"
+	CallDeleteConversation    CallKind = "deleteConversation"
+	CallCreateItems           CallKind = "createConversationItems"
+	CallListItems             CallKind = "listConversationItems"
+	CallGetItem               CallKind = "getConversationItem"
+	CallDeleteItem            CallKind = "deleteConversationItem"
+)
+
+// Invocation is an API call as seen by interceptors.
+type Invocation struct {
+	Kind CallKind
+	// Model is the name of the model of a model invocation, such as
+	// "yandexgpt-lite", and empty for other calls.
+	Model  string
+	Method string
+	URL    string
+	// Request is the request body: a pointer to the typed request, such as
+	// *CompletionRequest, a map for the conversations API, or nil for
+	// requests without a body. Interceptors may modify or replace it.
+	Request interface{}
+	// Response is a pointer to the typed response the result is decoded
+	// into, such as *CompletionResponse. It is filled in when the Invoker
+	// returns without error. An interceptor that answers the call itself
+	// fills it in and returns nil; it must not replace the pointer.
+	Response interface{}
+	// Header holds the headers set by call options. Changes apply to every
+	// attempt of the call.
+	Header http.Header
+}
+
+// Invoker sends an Invocation, with retries, and decodes the result into
+// inv.Response.
+type Invoker func(ctx context.Context, inv *Invocation) error
+
+// Interceptor wraps API calls. It may inspect or modify inv before calling
+// next, inspect the response or error afterwards, measure the call, or
+// answer it without calling next:
+//
+//	logging := func(ctx context.Context, inv *yandexgpt.Invocation, next yandexgpt.Invoker) error {
+//	    start := time.Now()
+//	    err := next(ctx, inv)
+//	    log.Printf("%s took %v: %v", inv.Kind, time.Since(start), err)
+//	    return err
+//	}
+//
+// A hedged completion runs the interceptors for every request it sends, and
+// a completion with fallbacks for every model it tries.
+type Interceptor func(ctx context.Context, inv *Invocation, next Invoker) error
+
+// WithInterceptors adds interceptors to the client. The first interceptor is
+// the outermost one. Clients derived from the client keep them.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// intercept runs the interceptors of the client around invoke.
+func (c *Client) intercept(ctx context.Context, inv *Invocation, invoke Invoker) error {
+	next := invoke
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.interceptors[i], next
+		next = func(ctx context.Context, inv *Invocation) error {
+			return interceptor(ctx, inv, inner)
+		}
+	}
+	return next(ctx, inv)
+}
@@ -0,0 +1,137 @@
+package yandexgpt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestInterceptorChain(t *testing.T) {
+	var received CompletionRequest
+	var header http.Header
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&received)
+		writeCompletion(w, "Hi")
+	})
+
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, inv *Invocation, next Invoker) error {
+			order = append(order, name+">")
+			err := next(ctx, inv)
+			order = append(order, "<"+name)
+			return err
+		}
+	}
+	modify := func(ctx context.Context, inv *Invocation, next Invoker) error {
+		if inv.Kind != CallCompletion || inv.Method != "POST" || inv.URL != CompletionEndpoint {
+			t.Errorf("Unexpected invocation: %s %s %s", inv.Kind, inv.Method, inv.URL)
+		}
+		request := inv.Request.(*CompletionRequest)
+		if request.ModelURI != "gpt://test_folder/yandexgpt-lite" {
+			t.Errorf("Unexpected model URI: %s", request.ModelURI)
+		}
+		request.CompletionOptions.MaxTokens = 42
+		inv.Header.Set("X-Intercepted", "yes")
+
+		if err := next(ctx, inv); err != nil {
+			return err
+		}
+
+		response := inv.Response.(*CompletionResponse)
+		response.Result.Alternatives[0].Message.Text += "!"
+		return nil
+	}
+
+	client = client.derive(func(d *Client) {
+		WithInterceptors(trace("outer"), trace("inner"), modify)(d)
+	})
+
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(order, " "); got != "outer> inner> <inner <outer" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+	if received.CompletionOptions.MaxTokens != 42 {
+		t.Errorf("Expected the modified request to be sent, got MaxTokens %d", received.CompletionOptions.MaxTokens)
+	}
+	if header.Get("X-Intercepted") != "yes" {
+		t.Error("Expected the header added by the interceptor")
+	}
+	if text := response.Result.Alternatives[0].Message.Text; text != "Hi!" {
+		t.Errorf("Expected the modified response, got %q", text)
+	}
+	if response.ResponseMetadata() == nil {
+		t.Error("Expected response metadata")
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request to be sent")
+	})
+
+	errBlocked := errors.New("blocked")
+	client = client.derive(func(d *Client) {
+		WithInterceptors(func(ctx context.Context, inv *Invocation, next Invoker) error {
+			switch inv.Kind {
+			case CallCompletion:
+				inv.Response.(*CompletionResponse).Result.Alternatives = []Alternative{
+					{Message: Message{Role: "assistant", Text: "cached"}},
+				}
+				return nil
+			default:
+				return errBlocked
+			}
+		})(d)
+	})
+
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := response.Result.Alternatives[0].Message.Text; text != "cached" {
+		t.Errorf("Expected the interceptor's response, got %q", text)
+	}
+
+	if _, err := client.Conversations().Get("c1"); !errors.Is(err, errBlocked) {
+		t.Errorf("Expected the interceptor's error, got %v", err)
+	}
+}
+
+func TestInterceptorKinds(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+
+	var kinds []CallKind
+	client = client.derive(func(d *Client) {
+		WithInterceptors(func(ctx context.Context, inv *Invocation, next Invoker) error {
+			kinds = append(kinds, inv.Kind)
+			return next(ctx, inv)
+		})(d)
+	})
+
+	client.Tokenize("Hello", models.YandexGPTLite)
+	client.GetOperation("op1")
+	client.WithFolder("other").Conversations().Delete("c1")
+
+	want := []CallKind{CallTokenize, CallGetOperation, CallDeleteConversation}
+	if len(kinds) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, kinds)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, kinds)
+		}
+	}
+}
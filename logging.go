@@ -0,0 +1,315 @@
+package yandexgpt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentLogging controls how prompts and generated text appear in logs.
+type ContentLogging int
+
+const (
+	// ContentOmit leaves prompts and generated text out of logs.
+	ContentOmit ContentLogging = iota
+	// ContentTruncate logs the first LogOptions.MaxContentLength characters.
+	ContentTruncate
+	// ContentHash logs the SHA-256 hash of the text, so equal prompts can be
+	// matched without revealing them.
+	ContentHash
+	// ContentFull logs the text as is.
+	ContentFull
+)
+
+// DefaultLogContentLength is the number of characters kept by
+// ContentTruncate unless LogOptions.MaxContentLength is set.
+const DefaultLogContentLength = 100
+
+// LogOptions configures the logging of a client. The zero value omits
+// prompts and generated text.
+type LogOptions struct {
+	Content ContentLogging
+	// MaxContentLength defaults to DefaultLogContentLength.
+	MaxContentLength int
+}
+
+// WithLogger makes the client log to logger: API calls at debug level when
+// they start and at info level when they end, or warn level when they
+// fail, as well as retries, IAM token refreshes and image generation polls.
+//
+// OAuth tokens, IAM tokens and API keys are always masked. Prompts and
+// generated text are logged according to opts, which may be nil.
+func WithLogger(logger *slog.Logger, opts *LogOptions) ClientOption {
+	return func(c *Client) {
+		if logger == nil {
+			c.logger = nil
+			return
+		}
+		l := &clientLogger{logger: logger, maxContent: DefaultLogContentLength}
+		if opts != nil {
+			l.content = opts.Content
+			if opts.MaxContentLength > 0 {
+				l.maxContent = opts.MaxContentLength
+			}
+		}
+		c.logger = l
+	}
+}
+
+// secretPatterns match credentials that must never be logged: Authorization
+// header values, IAM tokens (t1.), OAuth tokens (y0_ to y3_, AQAAAA) and API
+// keys (AQVN).
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(Bearer|Api-Key)\s+[^\s"',;]+`),
+	regexp.MustCompile(`\bt1\.[A-Za-z0-9_.\-]{16,}`),
+	regexp.MustCompile(`\by[0-3]_[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`\bAQAAAA[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`\bAQVN[A-Za-z0-9_\-]{16,}`),
+}
+
+const redacted = "[REDACTED]"
+
+// RedactSecrets masks OAuth tokens, IAM tokens, API keys and Authorization
+// header values in s.
+func RedactSecrets(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if scheme, _, ok := strings.Cut(match, " "); ok {
+				return scheme + " " + redacted
+			}
+			return redacted
+		})
+	}
+	return s
+}
+
+// isSecretHeader reports whether the values of a header must be masked.
+func isSecretHeader(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"authorization", "token", "key", "secret", "cookie"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+type clientLogger struct {
+	logger     *slog.Logger
+	content    ContentLogging
+	maxContent int
+}
+
+func (l *clientLogger) enabled(ctx context.Context, level slog.Level) bool {
+	return l != nil && l.logger.Enabled(ctx, level)
+}
+
+// text returns the logged form of prompt or generated text, or "" when
+// content is omitted.
+func (l *clientLogger) text(s string) string {
+	switch l.content {
+	case ContentFull:
+		return RedactSecrets(s)
+	case ContentTruncate:
+		// Secrets cut short by truncation would no longer be recognized.
+		s = RedactSecrets(s)
+		if utf8.RuneCountInString(s) > l.maxContent {
+			s = string([]rune(s)[:l.maxContent]) + "…"
+		}
+		return s
+	case ContentHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	default:
+		return ""
+	}
+}
+
+// loggedMessage is a message as written to logs.
+type loggedMessage struct {
+	Role string `json:"role"`
+	Text string `json:"text,omitempty"`
+}
+
+func (l *clientLogger) messages(messages []Message) slog.Attr {
+	logged := make([]loggedMessage, len(messages))
+	for i, m := range messages {
+		logged[i] = loggedMessage{Role: m.Role, Text: l.text(m.Text)}
+	}
+	return slog.Any("messages", logged)
+}
+
+func (l *clientLogger) contentAttr(key, s string) []slog.Attr {
+	if text := l.text(s); text != "" {
+		return []slog.Attr{slog.String(key, text)}
+	}
+	return nil
+}
+
+func headerAttr(header http.Header) slog.Attr {
+	attrs := make([]any, 0, len(header))
+	for key, values := range header {
+		value := strings.Join(values, ", ")
+		if isSecretHeader(key) {
+			value = redacted
+		}
+		attrs = append(attrs, slog.String(key, RedactSecrets(value)))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// request logs the start of an API call.
+func (l *clientLogger) request(ctx context.Context, kind CallKind, method, requestURL string, header http.Header, body interface{}) {
+	if !l.enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("kind", string(kind)),
+		slog.String("method", method),
+		slog.String("url", requestURL),
+	}
+	if model := requestModel(body); model != "" {
+		attrs = append(attrs, slog.String("model", model))
+	}
+	if len(header) > 0 {
+		attrs = append(attrs, headerAttr(header))
+	}
+
+	switch request := body.(type) {
+	case *CompletionRequest:
+		attrs = append(attrs,
+			slog.Float64("temperature", request.CompletionOptions.Temperature),
+			slog.Int("max_tokens", request.CompletionOptions.MaxTokens),
+		)
+		if l.content != ContentOmit {
+			attrs = append(attrs, l.messages(request.Messages))
+		}
+	case *TokenizeRequest:
+		attrs = append(attrs, l.contentAttr("text", request.Text)...)
+	case *TextEmbeddingRequest:
+		attrs = append(attrs, l.contentAttr("text", request.Text)...)
+	case *TextClassificationRequest:
+		attrs = append(attrs, l.contentAttr("text", request.Text)...)
+	case *FewShotClassificationRequest:
+		attrs = append(attrs, l.contentAttr("text", request.Text)...)
+	case *ImageGenerationRequest:
+		texts := make([]string, len(request.Messages))
+		for i, m := range request.Messages {
+			texts[i] = m.Text
+		}
+		attrs = append(attrs, l.contentAttr("prompt", strings.Join(texts, "\n"))...)
+	}
+
+	l.logger.LogAttrs(ctx, slog.LevelDebug, "yandexgpt request", attrs...)
+}
+
+// response logs the end of an API call.
+func (l *clientLogger) response(ctx context.Context, kind CallKind, body, result interface{}, elapsed time.Duration, err error) {
+	level := slog.LevelInfo
+	msg := "yandexgpt response"
+	if err != nil {
+		level, msg = slog.LevelWarn, "yandexgpt request failed"
+	}
+	if !l.enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("kind", string(kind)),
+		slog.Duration("duration", elapsed),
+	}
+	if model := requestModel(body); model != "" {
+		attrs = append(attrs, slog.String("model", model))
+	}
+
+	var metadata *ResponseMetadata
+	if err != nil {
+		metadata = ErrorMetadata(err)
+	} else if r, ok := result.(interface{ ResponseMetadata() *ResponseMetadata }); ok {
+		metadata = r.ResponseMetadata()
+	}
+	if metadata != nil {
+		attrs = append(attrs,
+			slog.Int("status", metadata.StatusCode),
+			slog.Int("attempts", metadata.Attempts),
+			slog.String("request_id", metadata.RequestID),
+			slog.String("client_request_id", metadata.ClientRequestID),
+		)
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", RedactSecrets(err.Error())))
+		l.logger.LogAttrs(ctx, level, msg, attrs...)
+		return
+	}
+
+	switch response := result.(type) {
+	case *CompletionResponse:
+		usage := response.Result.Usage
+		attrs = append(attrs,
+			slog.Int("input_tokens", usage.InputTextTokens),
+			slog.Int("completion_tokens", usage.CompletionTokens),
+			slog.Int("reasoning_tokens", usage.ReasoningTokens),
+		)
+		if len(response.Result.Alternatives) > 0 {
+			alternative := response.Result.Alternatives[0]
+			attrs = append(attrs, slog.String("finish_status", alternative.Status))
+			attrs = append(attrs, l.contentAttr("completion", alternative.Message.Text)...)
+		}
+	case *Operation:
+		attrs = append(attrs, slog.String("operation_id", response.ID), slog.Bool("done", response.Done))
+	}
+
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// retry logs a failed attempt that is retried.
+func (l *clientLogger) retry(ctx context.Context, kind CallKind, attempt int, backoff time.Duration, err error) {
+	if !l.enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "yandexgpt retry",
+		slog.String("kind", string(kind)),
+		slog.Int("attempt", attempt),
+		slog.Duration("backoff", backoff),
+		slog.String("error", RedactSecrets(err.Error())),
+	)
+}
+
+// iamRefresh logs an IAM token refresh. It is used as an OnIAMRefresh hook,
+// so it has no context of its own.
+func (l *clientLogger) iamRefresh(e IAMRefreshEvent) {
+	ctx := context.Background()
+	if e.Err != nil {
+		if l.enabled(ctx, slog.LevelWarn) {
+			l.logger.LogAttrs(ctx, slog.LevelWarn, "yandexgpt IAM token refresh failed",
+				slog.Duration("duration", e.Latency),
+				slog.String("error", RedactSecrets(e.Err.Error())),
+			)
+		}
+		return
+	}
+	if l.enabled(ctx, slog.LevelInfo) {
+		l.logger.LogAttrs(ctx, slog.LevelInfo, "yandexgpt IAM token refreshed", slog.Duration("duration", e.Latency))
+	}
+}
+
+// poll logs an iteration of image generation polling.
+func (l *clientLogger) poll(ctx context.Context, operationID string, iteration int, elapsed time.Duration, done bool) {
+	if !l.enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	l.logger.LogAttrs(ctx, slog.LevelDebug, "yandexgpt operation poll",
+		slog.String("operation_id", operationID),
+		slog.Int("iteration", iteration),
+		slog.Duration("elapsed", elapsed),
+		slog.Bool("done", done),
+	)
+}
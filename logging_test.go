@@ -0,0 +1,155 @@
+package yandexgpt
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+const testIAMToken = "t1.9euelZqKnM2TmZiJyo6SjZKNlJ6Sj-3rnpWal5WWi4yUzp6Yy5ySnY2bkc7l8_dBHXpt"
+
+func TestLogging(t *testing.T) {
+	var calls int32
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeCompletion(w, "The answer is forty-two")
+	},
+		WithLogger(logger, &LogOptions{Content: ContentTruncate, MaxContentLength: 10}),
+	)
+
+	_, err := client.GenerateText("What is the meaning of life?", models.YandexGPTLite, nil,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		WithHeader("X-Api-Key", "secret-value"),
+		WithHeader("X-Trace", "Bearer "+testIAMToken),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records[record["msg"].(string)] = record
+	}
+
+	for _, msg := range []string{"yandexgpt request", "yandexgpt retry", "yandexgpt IAM token refreshed", "yandexgpt response"} {
+		if _, ok := records[msg]; !ok {
+			t.Errorf("Missing %q record in:\n%s", msg, buf.String())
+		}
+	}
+
+	request := records["yandexgpt request"]
+	if request["level"] != "DEBUG" || request["kind"] != "completion" || request["model"] != "yandexgpt-lite" {
+		t.Errorf("Unexpected request record: %v", request)
+	}
+	messages, _ := request["messages"].([]interface{})
+	if len(messages) != 1 || messages[0].(map[string]interface{})["text"] != "What is th…" {
+		t.Errorf("Expected a truncated prompt, got %v", request["messages"])
+	}
+	headers, _ := request["headers"].(map[string]interface{})
+	if headers["X-Api-Key"] != redacted || headers["X-Trace"] != "Bearer "+redacted {
+		t.Errorf("Expected secret headers to be masked, got %v", headers)
+	}
+
+	response := records["yandexgpt response"]
+	if response["level"] != "INFO" || response["completion"] != "The answer…" || response["attempts"] != float64(2) {
+		t.Errorf("Unexpected response record: %v", response)
+	}
+
+	if strings.Contains(buf.String(), testIAMToken) || strings.Contains(buf.String(), "secret-value") {
+		t.Errorf("Secrets leaked into logs:\n%s", buf.String())
+	}
+}
+
+func TestLoggingFailure(t *testing.T) {
+	var buf bytes.Buffer
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"message":"token ` + testIAMToken + ` is not allowed"}}`))
+	},
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil)), nil),
+	)
+
+	if _, err := client.GenerateText("private prompt", models.YandexGPTLite, nil); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, `msg="yandexgpt request failed"`) {
+		t.Errorf("Expected a warning, got:\n%s", out)
+	}
+	if strings.Contains(out, testIAMToken) || strings.Contains(out, "private prompt") {
+		t.Errorf("Secrets or prompt leaked into logs:\n%s", out)
+	}
+	if strings.Contains(out, "yandexgpt request\"") || strings.Contains(out, "level=DEBUG") {
+		t.Errorf("Expected no debug records at info level:\n%s", out)
+	}
+}
+
+func TestLogContent(t *testing.T) {
+	tests := []struct {
+		content ContentLogging
+		want    string
+	}{
+		{ContentOmit, ""},
+		{ContentTruncate, "Привет…"},
+		{ContentHash, ""},
+		{ContentFull, "Привет, мир"},
+	}
+	for _, tt := range tests {
+		l := &clientLogger{content: tt.content, maxContent: 6}
+		got := l.text("Привет, мир")
+		if tt.content == ContentHash {
+			if !strings.HasPrefix(got, "sha256:") || len(got) != len("sha256:")+64 {
+				t.Errorf("Unexpected hash %q", got)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("text(%v) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestLogContentTruncatesRedacted(t *testing.T) {
+	// The token straddles MaxContentLength: its head alone is too short to
+	// be recognized as a secret.
+	l := &clientLogger{content: ContentTruncate, maxContent: 20}
+	got := l.text("token " + testIAMToken + " expired")
+	if strings.Contains(got, testIAMToken[:8]) || got != "token "+redacted+" exp…" {
+		t.Errorf("Expected the token to be redacted before truncation, got %q", got)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	tests := map[string]string{
+		"Authorization: Bearer " + testIAMToken:     "Authorization: Bearer " + redacted,
+		"Api-Key AQVNabc123":                        "Api-Key " + redacted,
+		"iam " + testIAMToken + " expired":          "iam " + redacted + " expired",
+		"oauth y0_AgAAAABkZXZfdG9rZW5fMTIzNDU2Nzg5": "oauth " + redacted,
+		"key AQVNxq3jzA1b2C3d4E5f6G7h8I9j0":         "key " + redacted,
+		"no secrets here":                           "no secrets here",
+	}
+	for in, want := range tests {
+		if got := RedactSecrets(in); got != want {
+			t.Errorf("RedactSecrets(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
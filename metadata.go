@@ -0,0 +1,134 @@
+package yandexgpt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Headers used to correlate a call with Yandex Cloud logs.
+const (
+	HeaderClientRequestID = "x-client-request-id"
+	HeaderRequestID       = "x-request-id"
+	HeaderServerTraceID   = "x-server-trace-id"
+)
+
+// ResponseMetadata describes the HTTP exchange behind a result or an error.
+// Quote RequestID and ServerTraceID when contacting Yandex Cloud support.
+type ResponseMetadata struct {
+	// ClientRequestID is the x-client-request-id sent with the request.
+	ClientRequestID string
+	// RequestID is the x-request-id assigned by the server.
+	RequestID string
+	// ServerTraceID is the x-server-trace-id assigned by the server.
+	ServerTraceID string
+	// StatusCode is the HTTP status code, or 0 if no response was received.
+	StatusCode int
+	// Latency is the time from sending the request to reading the response body.
+	Latency time.Duration
+	// Header holds the raw response headers.
+	Header http.Header
+	// Attempts is the number of attempts made, including retries.
+	Attempts int
+	// Tags are the tags attached to the call with WithTags.
+	Tags map[string]string
+	// Model is the model that served a completion, which differs from the
+	// requested model when a fallback model was used.
+	Model string
+	// Hedges is the number of duplicate requests sent by a hedged completion.
+	Hedges int
+	// Cached reports a completion served from the cache of WithCache. Only
+	// Tags and Model are set for cached completions.
+	Cached bool
+	// Coalesced reports a response shared with a concurrent identical call
+	// by WithRequestCoalescing.
+	Coalesced bool
+}
+
+// withMetadata is embedded in response types to expose their ResponseMetadata.
+type withMetadata struct {
+	metadata *ResponseMetadata
+}
+
+// ResponseMetadata returns the metadata of the HTTP exchange that produced
+// the result, or nil if the result was not received from the API.
+func (w *withMetadata) ResponseMetadata() *ResponseMetadata {
+	return w.metadata
+}
+
+func (w *withMetadata) setMetadata(m *ResponseMetadata) {
+	w.metadata = m
+}
+
+type metadataSetter interface {
+	setMetadata(*ResponseMetadata)
+}
+
+type clientRequestIDKey struct{}
+
+// WithClientRequestID returns a context that makes the client send id as the
+// x-client-request-id of requests made with it. Without it every request
+// gets a freshly generated ID.
+func WithClientRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientRequestIDKey{}, id)
+}
+
+func clientRequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(clientRequestIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return newRequestID()
+}
+
+// newRequestID returns a random RFC 4122 version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+func newResponseMetadata(clientID string, resp *http.Response, latency time.Duration) *ResponseMetadata {
+	m := &ResponseMetadata{
+		ClientRequestID: clientID,
+		Latency:         latency,
+	}
+	if resp != nil {
+		m.StatusCode = resp.StatusCode
+		m.Header = resp.Header
+		m.RequestID = resp.Header.Get(HeaderRequestID)
+		m.ServerTraceID = resp.Header.Get(HeaderServerTraceID)
+	}
+	return m
+}
+
+// ErrorMetadata returns the ResponseMetadata carried by err or by any error
+// it wraps, or nil if there is none.
+func ErrorMetadata(err error) *ResponseMetadata {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Metadata != nil {
+		return apiErr.Metadata
+	}
+	var authErr *AuthenticationError
+	if errors.As(err, &authErr) {
+		return authErr.Metadata
+	}
+	return nil
+}
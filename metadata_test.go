@@ -0,0 +1,105 @@
+package yandexgpt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestResponseMetadata(t *testing.T) {
+	var sentID string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sentID = r.Header.Get(HeaderClientRequestID)
+		w.Header().Set(HeaderRequestID, "req-1")
+		w.Header().Set(HeaderServerTraceID, "trace-1")
+		json.NewEncoder(w).Encode(CompletionResponse{Result: Result{
+			Alternatives: []Alternative{{Message: Message{Role: "assistant", Text: "Hi"}}},
+		}})
+	})
+
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !uuidPattern.MatchString(sentID) {
+		t.Errorf("Expected a generated UUID client request ID, got %q", sentID)
+	}
+
+	metadata := response.ResponseMetadata()
+	if metadata == nil {
+		t.Fatal("Expected response metadata")
+	}
+	if metadata.ClientRequestID != sentID {
+		t.Errorf("Expected client request ID %q, got %q", sentID, metadata.ClientRequestID)
+	}
+	if metadata.RequestID != "req-1" || metadata.ServerTraceID != "trace-1" {
+		t.Errorf("Unexpected IDs: %+v", metadata)
+	}
+	if metadata.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", metadata.StatusCode)
+	}
+	if metadata.Latency <= 0 {
+		t.Error("Expected positive latency")
+	}
+	if metadata.Header.Get(HeaderServerTraceID) != "trace-1" {
+		t.Error("Expected raw headers to be exposed")
+	}
+}
+
+func TestClientRequestIDFromContext(t *testing.T) {
+	var sentID string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sentID = r.Header.Get(HeaderClientRequestID)
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+
+	ctx := WithClientRequestID(context.Background(), "my-request")
+	if _, err := client.GenerateTextContext(ctx, "Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+	if sentID != "my-request" {
+		t.Errorf("Expected client request ID my-request, got %q", sentID)
+	}
+}
+
+func TestErrorMetadata(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderRequestID, "req-2")
+		w.Header().Set(HeaderServerTraceID, "trace-2")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":{"grpcCode":14,"httpCode":503,"message":"unavailable","httpStatus":"Service Unavailable"}}`))
+	})
+
+	ctx := WithClientRequestID(context.Background(), "failing-request")
+	_, err := client.GenerateTextContext(ctx, "Hello", models.YandexGPTLite, nil)
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	metadata := ErrorMetadata(err)
+	if metadata == nil {
+		t.Fatalf("Expected metadata on %T", err)
+	}
+	if metadata.ClientRequestID != "failing-request" || metadata.RequestID != "req-2" || metadata.ServerTraceID != "trace-2" {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+	if metadata.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", metadata.StatusCode)
+	}
+}
+
+func TestErrorMetadataAbsent(t *testing.T) {
+	if ErrorMetadata(NewAPIError("local", 0, nil)) != nil {
+		t.Error("Expected no metadata for a client-side error")
+	}
+	if ErrorMetadata(nil) != nil {
+		t.Error("Expected no metadata for nil")
+	}
+}
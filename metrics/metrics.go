@@ -0,0 +1,249 @@
+// Package metrics exports Prometheus metrics for the traffic of YandexGPT
+// clients: requests, latency, tokens, IAM token refreshes, retries, rate
+// limits, circuit breaker state changes and cache lookups.
+//
+//	collector := metrics.NewCollector(nil)
+//	prometheus.MustRegister(collector)
+//
+//	client, err := yandexgpt.NewClient(token, folderID, collector.ClientOption())
+//
+// One collector can instrument any number of clients. The package is a
+// module of its own, so that the SDK does not depend on Prometheus.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// DefaultNamespace prefixes the metric names unless Options.Namespace is set.
+const DefaultNamespace = "yandexgpt"
+
+// DefaultBuckets are the latency histogram buckets in seconds. Completions
+// take much longer than typical HTTP requests, so they reach a minute.
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60}
+
+// Options configures a Collector. The zero value is usable.
+type Options struct {
+	// Namespace defaults to DefaultNamespace.
+	Namespace string
+	// Buckets of the latency histograms. Defaults to DefaultBuckets.
+	Buckets []float64
+	// ConstLabels are added to every metric.
+	ConstLabels prometheus.Labels
+}
+
+// Collector is a prometheus.Collector fed by the interceptor and hooks of
+// the clients it instruments. Its metrics are, without the namespace prefix:
+//
+//	requests_total{endpoint, model, status}            calls, including every hedge and fallback model
+//	request_duration_seconds{endpoint, model, status}  call latency, including retries
+//	requests_in_flight{endpoint}                       calls in progress
+//	tokens_total{model, type}                          input, completion and reasoning tokens
+//	retries_total{endpoint, status}                    attempts that were retried, by their error
+//	rate_limited_total{endpoint}                       attempts rejected by a rate limit
+//	iam_refreshes_total{status}                        IAM token requests
+//	iam_refresh_duration_seconds{status}               IAM token request latency
+//	circuit_state{endpoint}                            0 closed, 1 open, 2 half-open
+//	circuit_state_changes_total{endpoint, state}       transitions into state
+//	cache_lookups_total{model, cache, result}          exact and semantic cache lookups: hit, miss or error
+//
+// The endpoint label is the yandexgpt.CallKind of the call, or the
+// yandexgpt.Endpoint family for circuit breakers. The status label is "ok"
+// or a short name of the error, such as "rate_limited" or "unavailable".
+//
+// circuit_state is the state after the last transition reported by any of
+// the clients. Clients with circuit breakers of their own should each get a
+// collector with distinct Options.ConstLabels, such as {"client": "search"}.
+type Collector struct {
+	requests       *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	inFlight       *prometheus.GaugeVec
+	tokens         *prometheus.CounterVec
+	retries        *prometheus.CounterVec
+	rateLimited    *prometheus.CounterVec
+	iamRefreshes   *prometheus.CounterVec
+	iamDuration    *prometheus.HistogramVec
+	circuitState   *prometheus.GaugeVec
+	circuitChanges *prometheus.CounterVec
+	cacheLookups   *prometheus.CounterVec
+	collectors     []prometheus.Collector
+}
+
+// NewCollector returns a collector. Register it with a prometheus.Registerer
+// and pass ClientOption to the clients to instrument.
+func NewCollector(opts *Options) *Collector {
+	namespace, buckets := DefaultNamespace, DefaultBuckets
+	var constLabels prometheus.Labels
+	if opts != nil {
+		if opts.Namespace != "" {
+			namespace = opts.Namespace
+		}
+		if len(opts.Buckets) > 0 {
+			buckets = opts.Buckets
+		}
+		constLabels = opts.ConstLabels
+	}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: name, Help: help, ConstLabels: constLabels,
+		}, labels)
+	}
+	gauge := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: name, Help: help, ConstLabels: constLabels,
+		}, labels)
+	}
+	histogram := func(name, help string, labels ...string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: name, Help: help, ConstLabels: constLabels, Buckets: buckets,
+		}, labels)
+	}
+
+	c := &Collector{
+		requests:       counter("requests_total", "API calls by endpoint, model and status.", "endpoint", "model", "status"),
+		duration:       histogram("request_duration_seconds", "API call latency, including retries.", "endpoint", "model", "status"),
+		inFlight:       gauge("requests_in_flight", "API calls in progress.", "endpoint"),
+		tokens:         counter("tokens_total", "Tokens used by completions, by type: input, completion or reasoning.", "model", "type"),
+		retries:        counter("retries_total", "Attempts that were retried, by the status of the failed attempt.", "endpoint", "status"),
+		rateLimited:    counter("rate_limited_total", "Attempts rejected by a rate limit.", "endpoint"),
+		iamRefreshes:   counter("iam_refreshes_total", "IAM token requests by status.", "status"),
+		iamDuration:    histogram("iam_refresh_duration_seconds", "IAM token request latency.", "status"),
+		circuitState:   gauge("circuit_state", "Circuit breaker state: 0 closed, 1 open, 2 half-open.", "endpoint"),
+		circuitChanges: counter("circuit_state_changes_total", "Circuit breaker transitions by target state.", "endpoint", "state"),
+		cacheLookups:   counter("cache_lookups_total", "Response cache lookups by cache, exact or semantic, and result: hit, miss or error.", "model", "cache", "result"),
+	}
+	c.collectors = []prometheus.Collector{
+		c.requests, c.duration, c.inFlight, c.tokens, c.retries, c.rateLimited,
+		c.iamRefreshes, c.iamDuration, c.circuitState, c.circuitChanges, c.cacheLookups,
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors {
+		collector.Collect(ch)
+	}
+}
+
+// ClientOption instruments a client with the interceptor and hooks of c.
+func (c *Collector) ClientOption() yandexgpt.ClientOption {
+	interceptors := yandexgpt.WithInterceptors(c.Interceptor())
+	hooks := yandexgpt.WithHooks(c.Hooks())
+	return func(client *yandexgpt.Client) {
+		interceptors(client)
+		hooks(client)
+	}
+}
+
+// Interceptor records the requests, latency and tokens of every call.
+func (c *Collector) Interceptor() yandexgpt.Interceptor {
+	return func(ctx context.Context, inv *yandexgpt.Invocation, next yandexgpt.Invoker) error {
+		endpoint := string(inv.Kind)
+		inFlight := c.inFlight.WithLabelValues(endpoint)
+
+		inFlight.Inc()
+		start := time.Now()
+		err := next(ctx, inv)
+		elapsed := time.Since(start)
+		inFlight.Dec()
+
+		status := Status(err)
+		c.requests.WithLabelValues(endpoint, inv.Model, status).Inc()
+		c.duration.WithLabelValues(endpoint, inv.Model, status).Observe(elapsed.Seconds())
+
+		if response, ok := inv.Response.(*yandexgpt.CompletionResponse); ok && err == nil {
+			usage := response.Result.Usage
+			c.tokens.WithLabelValues(inv.Model, "input").Add(float64(usage.InputTextTokens))
+			c.tokens.WithLabelValues(inv.Model, "completion").Add(float64(usage.CompletionTokens))
+			c.tokens.WithLabelValues(inv.Model, "reasoning").Add(float64(usage.ReasoningTokens))
+		}
+		return err
+	}
+}
+
+// Hooks records retries, rate limits, IAM token refreshes, circuit breaker
+// state changes and cache lookups.
+func (c *Collector) Hooks() yandexgpt.Hooks {
+	return yandexgpt.Hooks{
+		OnAttempt: func(e yandexgpt.AttemptEvent) {
+			if e.Retry {
+				c.retries.WithLabelValues(string(e.Kind), Status(e.Err)).Inc()
+			}
+			if errors.Is(e.Err, yandexgpt.ErrRateLimited) {
+				c.rateLimited.WithLabelValues(string(e.Kind)).Inc()
+			}
+		},
+		OnIAMRefresh: func(e yandexgpt.IAMRefreshEvent) {
+			status := Status(e.Err)
+			c.iamRefreshes.WithLabelValues(status).Inc()
+			c.iamDuration.WithLabelValues(status).Observe(e.Latency.Seconds())
+		},
+		OnCircuitStateChange: func(e yandexgpt.CircuitEvent) {
+			c.circuitState.WithLabelValues(string(e.Endpoint)).Set(float64(e.To))
+			c.circuitChanges.WithLabelValues(string(e.Endpoint), e.To.String()).Inc()
+		},
+		OnCacheLookup: func(e yandexgpt.CacheEvent) {
+			result := "miss"
+			switch {
+			case e.Err != nil:
+				result = "error"
+			case e.Hit:
+				result = "hit"
+			}
+			cache := "exact"
+			if e.Semantic {
+				cache = "semantic"
+			}
+			c.cacheLookups.WithLabelValues(e.Model, cache, result).Inc()
+		},
+	}
+}
+
+// Status returns the value of the status label for err.
+func Status(err error) string {
+	var authErr *yandexgpt.AuthenticationError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, yandexgpt.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, yandexgpt.ErrQuotaExceeded):
+		return "quota_exceeded"
+	case errors.Is(err, yandexgpt.ErrPermissionDenied):
+		return "permission_denied"
+	case errors.Is(err, yandexgpt.ErrInvalidArgument):
+		return "invalid_argument"
+	case errors.Is(err, yandexgpt.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, yandexgpt.ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, yandexgpt.ErrContentFiltered):
+		return "content_filtered"
+	case errors.Is(err, yandexgpt.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, yandexgpt.ErrBudgetExceeded):
+		return "budget_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.As(err, &authErr):
+		return "authentication"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+	"github.com/tigusigalpa/yandexgpt-go/v2/yandexgpttest"
+)
+
+func TestCollector(t *testing.T) {
+	server := yandexgpttest.NewServer(nil)
+	defer server.Close()
+	server.Fail(yandexgpt.CallCompletion, yandexgpttest.Fault{Status: http.StatusTooManyRequests})
+	server.Script(yandexgpttest.Reply{Text: "Hi", Usage: &yandexgpt.Usage{InputTextTokens: 5, CompletionTokens: 7, TotalTokens: 12}})
+
+	collector := NewCollector(nil)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	client := server.NewClient(
+		collector.ClientOption(),
+		yandexgpt.WithCircuitBreaker(yandexgpt.CircuitBreakerPolicy{}),
+	)
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		yandexgpt.WithRetryPolicy(yandexgpt.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		collector prometheus.Collector
+		want      float64
+	}{
+		{"requests", collector.requests.WithLabelValues("completion", "yandexgpt-lite", "ok"), 1},
+		{"input tokens", collector.tokens.WithLabelValues("yandexgpt-lite", "input"), 5},
+		{"completion tokens", collector.tokens.WithLabelValues("yandexgpt-lite", "completion"), 7},
+		{"retries", collector.retries.WithLabelValues("completion", "rate_limited"), 1},
+		{"rate limits", collector.rateLimited.WithLabelValues("completion"), 1},
+		{"IAM refreshes", collector.iamRefreshes.WithLabelValues("ok"), 1},
+		{"in flight", collector.inFlight.WithLabelValues("completion"), 0},
+	}
+	for _, check := range checks {
+		if got := testutil.ToFloat64(check.collector); got != check.want {
+			t.Errorf("%s = %v, want %v", check.name, got, check.want)
+		}
+	}
+
+	if n := testutil.CollectAndCount(collector, "yandexgpt_request_duration_seconds"); n != 1 {
+		t.Errorf("Expected 1 latency histogram, got %d", n)
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP yandexgpt_requests_total API calls by endpoint, model and status.
+# TYPE yandexgpt_requests_total counter
+yandexgpt_requests_total{endpoint="completion",model="yandexgpt-lite",status="ok"} 1
+`), "yandexgpt_requests_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorCircuitState(t *testing.T) {
+	collector := NewCollector(&Options{Namespace: "llm"})
+	collector.Hooks().OnCircuitStateChange(yandexgpt.CircuitEvent{
+		Endpoint: yandexgpt.EndpointImage,
+		From:     yandexgpt.CircuitClosed,
+		To:       yandexgpt.CircuitOpen,
+	})
+
+	if got := testutil.ToFloat64(collector.circuitState.WithLabelValues("image")); got != 1 {
+		t.Errorf("Expected open state 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(collector.circuitChanges.WithLabelValues("image", "open")); got != 1 {
+		t.Errorf("Expected 1 transition, got %v", got)
+	}
+	if n := testutil.CollectAndCount(collector, "llm_circuit_state"); n != 1 {
+		t.Errorf("Expected the namespace to apply, got %d llm_circuit_state series", n)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := map[string]error{
+		"ok":               nil,
+		"rate_limited":     yandexgpt.NewErrorFromResponse(429, http.Header{}, nil),
+		"unavailable":      yandexgpt.NewErrorFromResponse(503, http.Header{}, nil),
+		"invalid_argument": yandexgpt.NewErrorFromResponse(400, http.Header{}, nil),
+		"authentication":   yandexgpt.NewAuthenticationError("bad token", nil),
+		"error":            yandexgpt.NewAPIError("failed", 0, nil),
+		"budget_exceeded":  &yandexgpt.BudgetExceededError{},
+	}
+	for want, err := range tests {
+		if got := Status(err); got != want {
+			t.Errorf("Status(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestCollectorCacheLookups(t *testing.T) {
+	collector := NewCollector(nil)
+	hooks := collector.Hooks()
+	hooks.OnCacheLookup(yandexgpt.CacheEvent{Model: "yandexgpt-lite", Hit: true})
+	hooks.OnCacheLookup(yandexgpt.CacheEvent{Model: "yandexgpt-lite"})
+	hooks.OnCacheLookup(yandexgpt.CacheEvent{Model: "yandexgpt-lite", Err: errors.New("disk full")})
+	hooks.OnCacheLookup(yandexgpt.CacheEvent{Model: "yandexgpt-lite", Semantic: true, Hit: true})
+
+	for _, result := range []string{"hit", "miss", "error"} {
+		if got := testutil.ToFloat64(collector.cacheLookups.WithLabelValues("yandexgpt-lite", "exact", result)); got != 1 {
+			t.Errorf("Expected 1 exact %s, got %v", result, got)
+		}
+	}
+	if got := testutil.ToFloat64(collector.cacheLookups.WithLabelValues("yandexgpt-lite", "semantic", "hit")); got != 1 {
+		t.Errorf("Expected 1 semantic hit, got %v", got)
+	}
+}
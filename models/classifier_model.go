@@ -0,0 +1,26 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	YandexGPTClassifier = "yandexgpt/latest"
+)
+
+// GetClassifierModelURI returns the URI of the YandexGPT zero-shot and
+// few-shot classifier in the given catalog.
+func GetClassifierModelURI(catalogID string) string {
+	return fmt.Sprintf("cls://%s/%s", catalogID, YandexGPTClassifier)
+}
+
+// GetTunedClassifierModelURI returns the URI of a fine-tuned classifier. A
+// full "cls://" URI is returned unchanged; a bare model ID is placed into
+// the given catalog.
+func GetTunedClassifierModelURI(model, catalogID string) string {
+	if strings.HasPrefix(model, "cls://") {
+		return model
+	}
+	return fmt.Sprintf("cls://%s/%s", catalogID, model)
+}
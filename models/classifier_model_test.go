@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestGetClassifierModelURI(t *testing.T) {
+	expected := "cls://test-catalog/yandexgpt/latest"
+	if result := GetClassifierModelURI("test-catalog"); result != expected {
+		t.Errorf("GetClassifierModelURI(test-catalog) = %s, expected %s", result, expected)
+	}
+}
+
+func TestGetTunedClassifierModelURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		expected string
+	}{
+		{"Model ID", "bt1abc", "cls://test-catalog/bt1abc"},
+		{"Full URI", "cls://other-catalog/bt1abc", "cls://other-catalog/bt1abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetTunedClassifierModelURI(tt.model, "test-catalog")
+			if result != tt.expected {
+				t.Errorf("GetTunedClassifierModelURI(%s) = %s, expected %s", tt.model, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Text embedding models: documents are embedded with TextSearchDoc and the
+// queries searching them with TextSearchQuery.
+const (
+	TextSearchDoc   = "text-search-doc/latest"
+	TextSearchQuery = "text-search-query/latest"
+)
+
+// GetEmbeddingModelURI returns the URI of a text embedding model. A full
+// "emb://" URI is returned unchanged; a model name is placed into the given
+// catalog.
+func GetEmbeddingModelURI(model, catalogID string) string {
+	if strings.HasPrefix(model, "emb://") {
+		return model
+	}
+	return fmt.Sprintf("emb://%s/%s", catalogID, model)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestGetEmbeddingModelURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		expected string
+	}{
+		{"Document model", TextSearchDoc, "emb://test-catalog/text-search-doc/latest"},
+		{"Query model", TextSearchQuery, "emb://test-catalog/text-search-query/latest"},
+		{"Full URI", "emb://other-catalog/text-search-doc/rc", "emb://other-catalog/text-search-doc/rc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetEmbeddingModelURI(tt.model, "test-catalog")
+			if result != tt.expected {
+				t.Errorf("GetEmbeddingModelURI(%s) = %s, expected %s", tt.model, result, tt.expected)
+			}
+		})
+	}
+}
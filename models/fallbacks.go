@@ -0,0 +1,15 @@
+package models
+
+// DefaultFallbacks maps built-in models to the models to fall back to, in
+// order, when they are rate-limited or unavailable. Cheaper and less loaded
+// models come last.
+var DefaultFallbacks = map[string][]string{
+	AliceAI:   {YandexGPT, YandexGPTLite},
+	YandexGPT: {YandexGPTLite},
+}
+
+// Fallbacks returns the fallback chain of the named model from
+// DefaultFallbacks, or nil if it has none.
+func Fallbacks(name string) []string {
+	return append([]string(nil), DefaultFallbacks[name]...)
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Model branches. Any other well-formed branch value is treated as a pinned
+// model version.
+const (
+	BranchLatest     = "latest"
+	BranchRC         = "rc"
+	BranchDeprecated = "deprecated"
+)
+
+// URI schemes of text generation models.
+const (
+	// SchemeGPT covers foundation models and models fine-tuned in AI Studio.
+	SchemeGPT = "gpt"
+	// SchemeDataSphere covers models deployed in DataSphere.
+	SchemeDataSphere = "ds"
+)
+
+var segmentPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ModelRef identifies a text generation model: a built-in model, optionally
+// on a specific branch or version, a fine-tuned model, a DataSphere
+// deployment or a model from another folder.
+//
+// A ModelRef is written as a string in one of these forms, and String
+// produces a form that ParseModelRef accepts back:
+//
+//	yandexgpt                          built-in model in the client's folder
+//	yandexgpt/rc                       branch or pinned version
+//	yandexgpt-lite/latest@tamr1abc     fine-tuned model
+//	gpt://b1gfolder/yandexgpt/rc       model from an explicit folder
+//	ds://bt1deployment                 DataSphere deployment
+//
+// Client.GenerateTextRef and Client.GenerateFromMessagesRef take a ModelRef.
+// The other client methods take the model as a string, so pass ref.String()
+// to them.
+type ModelRef struct {
+	// Scheme is SchemeGPT or SchemeDataSphere. Empty means SchemeGPT.
+	Scheme string
+	// Folder is the folder owning the model. Empty means the client's folder.
+	Folder string
+	// Name is the model name, or the deployment ID for SchemeDataSphere.
+	Name string
+	// Branch is a branch such as BranchRC or a pinned version. Empty means
+	// the default branch.
+	Branch string
+	// Tuning is the fine-tuning suffix that follows '@' in the model URI.
+	Tuning string
+}
+
+// Model returns a reference to the named model in the client's folder.
+func Model(name string) ModelRef {
+	return ModelRef{Scheme: SchemeGPT, Name: name}
+}
+
+// DataSphere returns a reference to a model deployed in DataSphere.
+func DataSphere(deploymentID string) ModelRef {
+	return ModelRef{Scheme: SchemeDataSphere, Name: deploymentID}
+}
+
+// WithBranch returns a copy of r on the given branch or pinned version.
+func (r ModelRef) WithBranch(branch string) ModelRef {
+	r.Branch = branch
+	return r
+}
+
+// WithTuning returns a copy of r referring to a fine-tuned variant.
+func (r ModelRef) WithTuning(suffix string) ModelRef {
+	r.Tuning = suffix
+	return r
+}
+
+// InFolder returns a copy of r owned by the given folder.
+func (r ModelRef) InFolder(folderID string) ModelRef {
+	r.Folder = folderID
+	return r
+}
+
+// ParseModelRef parses a model in any of the forms listed on ModelRef.
+// Unknown model names are accepted as long as they are well-formed.
+func ParseModelRef(s string) (ModelRef, error) {
+	var ref ModelRef
+	var err error
+
+	if scheme, rest, ok := strings.Cut(s, "://"); ok {
+		switch scheme {
+		case SchemeDataSphere:
+			ref = DataSphere(rest)
+		case SchemeGPT:
+			folder, path, _ := strings.Cut(rest, "/")
+			if folder == "" {
+				return ModelRef{}, fmt.Errorf("model %q: folder is missing", s)
+			}
+			ref, err = parsePath(path)
+			ref.Folder = folder
+		default:
+			return ModelRef{}, fmt.Errorf("model %q: unsupported scheme %q", s, scheme)
+		}
+	} else {
+		ref, err = parsePath(s)
+	}
+
+	if err == nil {
+		err = ref.Validate()
+	}
+	if err != nil {
+		return ModelRef{}, fmt.Errorf("model %q: %w", s, err)
+	}
+	return ref, nil
+}
+
+func parsePath(path string) (ModelRef, error) {
+	path, tuning, hasTuning := strings.Cut(path, "@")
+	name, branch, hasBranch := strings.Cut(path, "/")
+	if (hasTuning && tuning == "") || (hasBranch && branch == "") {
+		return ModelRef{}, fmt.Errorf("empty branch or tuning suffix")
+	}
+	return ModelRef{Scheme: SchemeGPT, Name: name, Branch: branch, Tuning: tuning}, nil
+}
+
+// Validate reports whether r is well-formed. It does not check that the
+// model exists.
+func (r ModelRef) Validate() error {
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = SchemeGPT
+	}
+	if scheme != SchemeGPT && scheme != SchemeDataSphere {
+		return fmt.Errorf("unsupported scheme %q", r.Scheme)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("model name is empty")
+	}
+
+	fields := []struct{ name, value string }{
+		{"name", r.Name},
+		{"folder", r.Folder},
+		{"branch", r.Branch},
+		{"tuning suffix", r.Tuning},
+	}
+	for _, f := range fields {
+		if f.value != "" && !segmentPattern.MatchString(f.value) {
+			return fmt.Errorf("invalid %s %q", f.name, f.value)
+		}
+	}
+
+	if scheme == SchemeDataSphere && (r.Folder != "" || r.Branch != "" || r.Tuning != "") {
+		return fmt.Errorf("DataSphere models take neither folder, branch nor tuning suffix")
+	}
+	return nil
+}
+
+// IsBuiltin reports whether r refers to one of the models listed by GetAllModels.
+func (r ModelRef) IsBuiltin() bool {
+	return (r.Scheme == "" || r.Scheme == SchemeGPT) && IsValidModel(r.Name)
+}
+
+// URI returns the model URI sent to the API. folderID is used when r does
+// not name a folder of its own.
+func (r ModelRef) URI(folderID string) string {
+	if r.Scheme == SchemeDataSphere {
+		return fmt.Sprintf("%s://%s", SchemeDataSphere, r.Name)
+	}
+	if r.Folder != "" {
+		folderID = r.Folder
+	}
+	return fmt.Sprintf("%s://%s/%s", SchemeGPT, folderID, r.path())
+}
+
+// String returns r in a form accepted by ParseModelRef.
+func (r ModelRef) String() string {
+	if r.Scheme == SchemeDataSphere || r.Folder != "" {
+		return r.URI(r.Folder)
+	}
+	return r.path()
+}
+
+func (r ModelRef) path() string {
+	path := r.Name
+	if r.Branch != "" {
+		path += "/" + r.Branch
+	}
+	if r.Tuning != "" {
+		path += "@" + r.Tuning
+	}
+	return path
+}
@@ -0,0 +1,111 @@
+package models
+
+import "testing"
+
+func TestParseModelRef(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ModelRef
+		uri      string
+	}{
+		{
+			name:     "Built-in model",
+			input:    YandexGPT,
+			expected: ModelRef{Scheme: SchemeGPT, Name: YandexGPT},
+			uri:      "gpt://test-folder/yandexgpt",
+		},
+		{
+			name:     "Release candidate branch",
+			input:    "yandexgpt/rc",
+			expected: ModelRef{Scheme: SchemeGPT, Name: YandexGPT, Branch: BranchRC},
+			uri:      "gpt://test-folder/yandexgpt/rc",
+		},
+		{
+			name:     "Pinned version",
+			input:    "yandexgpt-lite/23.10.2024",
+			expected: ModelRef{Scheme: SchemeGPT, Name: YandexGPTLite, Branch: "23.10.2024"},
+			uri:      "gpt://test-folder/yandexgpt-lite/23.10.2024",
+		},
+		{
+			name:     "Fine-tuned model",
+			input:    "yandexgpt-lite/latest@tamr1abc",
+			expected: ModelRef{Scheme: SchemeGPT, Name: YandexGPTLite, Branch: BranchLatest, Tuning: "tamr1abc"},
+			uri:      "gpt://test-folder/yandexgpt-lite/latest@tamr1abc",
+		},
+		{
+			name:     "Other folder",
+			input:    "gpt://other-folder/yandexgpt/deprecated",
+			expected: ModelRef{Scheme: SchemeGPT, Folder: "other-folder", Name: YandexGPT, Branch: BranchDeprecated},
+			uri:      "gpt://other-folder/yandexgpt/deprecated",
+		},
+		{
+			name:     "DataSphere",
+			input:    "ds://bt1deployment",
+			expected: ModelRef{Scheme: SchemeDataSphere, Name: "bt1deployment"},
+			uri:      "ds://bt1deployment",
+		},
+		{
+			name:     "Unknown but well-formed model",
+			input:    "qwen3-235b-a22b-fp8/latest",
+			expected: ModelRef{Scheme: SchemeGPT, Name: "qwen3-235b-a22b-fp8", Branch: BranchLatest},
+			uri:      "gpt://test-folder/qwen3-235b-a22b-fp8/latest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseModelRef(tt.input)
+			if err != nil {
+				t.Fatalf("ParseModelRef(%s) returned error: %v", tt.input, err)
+			}
+			if ref != tt.expected {
+				t.Errorf("ParseModelRef(%s) = %+v, expected %+v", tt.input, ref, tt.expected)
+			}
+			if uri := ref.URI("test-folder"); uri != tt.uri {
+				t.Errorf("URI() = %s, expected %s", uri, tt.uri)
+			}
+			if ref.String() != tt.input {
+				t.Errorf("String() = %s, expected %s", ref.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestParseModelRefInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"gpt://",
+		"gpt:///yandexgpt",
+		"art://folder/yandex-art/latest",
+		"yandexgpt/rc/extra",
+		"yandex gpt",
+		"yandexgpt@",
+		"ds://folder/model",
+	}
+
+	for _, input := range inputs {
+		if _, err := ParseModelRef(input); err == nil {
+			t.Errorf("ParseModelRef(%q) expected error", input)
+		}
+	}
+}
+
+func TestModelRefBuilders(t *testing.T) {
+	ref := Model(YandexGPT).WithBranch(BranchRC).InFolder("other-folder")
+	if uri := ref.URI("test-folder"); uri != "gpt://other-folder/yandexgpt/rc" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	if !ref.IsBuiltin() {
+		t.Error("Expected yandexgpt to be built-in")
+	}
+
+	tuned := Model(YandexGPTLite).WithBranch(BranchLatest).WithTuning("tamr1abc")
+	if parsed, err := ParseModelRef(tuned.String()); err != nil || parsed != tuned {
+		t.Errorf("Round trip of %s failed: %+v, %v", tuned, parsed, err)
+	}
+
+	if DataSphere("bt1").IsBuiltin() {
+		t.Error("Expected DataSphere model not to be built-in")
+	}
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pricing holds model prices per 1000 tokens.
+type Pricing struct {
+	Currency string `json:"currency" yaml:"currency"`
+	// SyncPer1K applies to synchronous completion requests.
+	SyncPer1K float64 `json:"syncPer1K" yaml:"syncPer1K"`
+	// AsyncPer1K applies to asynchronous (batch) completion requests.
+	AsyncPer1K float64 `json:"asyncPer1K" yaml:"asyncPer1K"`
+}
+
+// ModelInfo describes the limits, capabilities and prices of a model.
+type ModelInfo struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	// ContextWindow is the maximum number of input and output tokens together.
+	ContextWindow int `json:"contextWindow" yaml:"contextWindow"`
+	// MaxOutputTokens is the largest accepted CompletionOptions.MaxTokens.
+	MaxOutputTokens          int     `json:"maxOutputTokens" yaml:"maxOutputTokens"`
+	SupportsReasoning        bool    `json:"supportsReasoning" yaml:"supportsReasoning"`
+	SupportsTools            bool    `json:"supportsTools" yaml:"supportsTools"`
+	SupportsStructuredOutput bool    `json:"supportsStructuredOutput" yaml:"supportsStructuredOutput"`
+	Pricing                  Pricing `json:"pricing" yaml:"pricing"`
+}
+
+// Registry is a concurrency-safe set of ModelInfo keyed by model name.
+type Registry struct {
+	mu     sync.RWMutex
+	models map[string]ModelInfo
+}
+
+// NewRegistry returns a registry holding the given models.
+func NewRegistry(infos ...ModelInfo) *Registry {
+	r := &Registry{models: make(map[string]ModelInfo, len(infos))}
+	for _, info := range infos {
+		r.Register(info)
+	}
+	return r
+}
+
+// DefaultRegistry describes the built-in models and is used by the client to
+// validate requests. Prices are list prices in rubles including VAT at the
+// time of writing; override them with Register or LoadFile when they change.
+var DefaultRegistry = NewRegistry(
+	ModelInfo{
+		Name:                     YandexGPTLite,
+		Description:              modelDescriptions[YandexGPTLite],
+		ContextWindow:            32768,
+		MaxOutputTokens:          32768,
+		SupportsStructuredOutput: true,
+		Pricing:                  Pricing{Currency: "RUB", SyncPer1K: 0.2, AsyncPer1K: 0.1},
+	},
+	ModelInfo{
+		Name:                     YandexGPT,
+		Description:              modelDescriptions[YandexGPT],
+		ContextWindow:            32768,
+		MaxOutputTokens:          32768,
+		SupportsReasoning:        true,
+		SupportsTools:            true,
+		SupportsStructuredOutput: true,
+		Pricing:                  Pricing{Currency: "RUB", SyncPer1K: 1.2, AsyncPer1K: 0.6},
+	},
+	ModelInfo{
+		Name:                     AliceAI,
+		Description:              modelDescriptions[AliceAI],
+		ContextWindow:            32768,
+		MaxOutputTokens:          32768,
+		SupportsReasoning:        true,
+		SupportsTools:            true,
+		SupportsStructuredOutput: true,
+		Pricing:                  Pricing{Currency: "RUB", SyncPer1K: 0.5, AsyncPer1K: 0.25},
+	},
+)
+
+// Register adds info to the registry, replacing any model with the same name.
+func (r *Registry) Register(info ModelInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.models[info.Name] = info
+}
+
+// Lookup returns the model registered under name.
+func (r *Registry) Lookup(name string) (ModelInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	info, ok := r.models[name]
+	return info, ok
+}
+
+// All returns every registered model, sorted by name.
+func (r *Registry) All() []ModelInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]ModelInfo, 0, len(r.models))
+	for _, info := range r.models {
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// LoadJSON reads a JSON array of models. Entries for already registered
+// models only override the fields they contain, so a file may, for example,
+// update prices alone.
+func (r *Registry) LoadJSON(reader io.Reader) error {
+	var entries []json.RawMessage
+	if err := json.NewDecoder(reader).Decode(&entries); err != nil {
+		return fmt.Errorf("models: decode registry: %w", err)
+	}
+	return r.load(len(entries), func(i int, info *ModelInfo) error {
+		return json.Unmarshal(entries[i], info)
+	})
+}
+
+// LoadYAML reads a YAML sequence of models with the same field names and
+// merge rules as LoadJSON.
+func (r *Registry) LoadYAML(reader io.Reader) error {
+	var entries []yaml.Node
+	if err := yaml.NewDecoder(reader).Decode(&entries); err != nil && err != io.EOF {
+		return fmt.Errorf("models: decode registry: %w", err)
+	}
+	return r.load(len(entries), func(i int, info *ModelInfo) error {
+		return entries[i].Decode(info)
+	})
+}
+
+// LoadFile reads models from a .json, .yaml or .yml file.
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("models: read registry: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return r.LoadJSON(bytes.NewReader(data))
+	case ".yaml", ".yml":
+		return r.LoadYAML(bytes.NewReader(data))
+	default:
+		return fmt.Errorf("models: unsupported registry file %s", path)
+	}
+}
+
+// load decodes all entries before registering any, so a malformed file
+// leaves the registry untouched.
+func (r *Registry) load(n int, decode func(i int, info *ModelInfo) error) error {
+	infos := make([]ModelInfo, 0, n)
+	for i := 0; i < n; i++ {
+		var probe ModelInfo
+		if err := decode(i, &probe); err != nil {
+			return fmt.Errorf("models: decode registry entry %d: %w", i, err)
+		}
+		if probe.Name == "" {
+			return fmt.Errorf("models: registry entry %d has no name", i)
+		}
+
+		// Decode again on top of the registered model to keep absent fields.
+		info, _ := r.Lookup(probe.Name)
+		if err := decode(i, &info); err != nil {
+			return fmt.Errorf("models: decode registry entry %d: %w", i, err)
+		}
+		infos = append(infos, info)
+	}
+
+	for _, info := range infos {
+		r.Register(info)
+	}
+	return nil
+}
+
+// Register adds info to DefaultRegistry.
+func Register(info ModelInfo) {
+	DefaultRegistry.Register(info)
+}
+
+// Lookup returns the model registered in DefaultRegistry under name.
+func Lookup(name string) (ModelInfo, bool) {
+	return DefaultRegistry.Lookup(name)
+}
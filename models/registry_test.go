@@ -0,0 +1,101 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	for _, name := range GetAllModels() {
+		info, ok := Lookup(name)
+		if !ok {
+			t.Errorf("Expected %s in DefaultRegistry", name)
+			continue
+		}
+		if info.ContextWindow == 0 || info.Pricing.SyncPer1K == 0 {
+			t.Errorf("Expected limits and pricing for %s, got %+v", name, info)
+		}
+	}
+
+	if info, _ := Lookup(YandexGPTLite); info.SupportsReasoning {
+		t.Error("Expected YandexGPTLite not to support reasoning")
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry(ModelInfo{Name: "a", ContextWindow: 1}, ModelInfo{Name: "b"})
+	r.Register(ModelInfo{Name: "a", ContextWindow: 2})
+
+	if info, _ := r.Lookup("a"); info.ContextWindow != 2 {
+		t.Errorf("Expected Register to replace model, got %+v", info)
+	}
+	if all := r.All(); len(all) != 2 || all[0].Name != "a" {
+		t.Errorf("Unexpected All() result %+v", all)
+	}
+}
+
+func TestRegistryLoadJSON(t *testing.T) {
+	r := NewRegistry(ModelInfo{
+		Name:              YandexGPT,
+		ContextWindow:     32768,
+		SupportsReasoning: true,
+		Pricing:           Pricing{Currency: "RUB", SyncPer1K: 1.2, AsyncPer1K: 0.6},
+	})
+
+	err := r.LoadJSON(strings.NewReader(`[
+		{"name": "yandexgpt", "pricing": {"syncPer1K": 1.5}},
+		{"name": "custom-model", "contextWindow": 8192, "supportsTools": true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := r.Lookup(YandexGPT)
+	if info.Pricing.SyncPer1K != 1.5 || info.Pricing.AsyncPer1K != 0.6 || !info.SupportsReasoning {
+		t.Errorf("Expected only sync price to change, got %+v", info)
+	}
+	if custom, ok := r.Lookup("custom-model"); !ok || custom.ContextWindow != 8192 || !custom.SupportsTools {
+		t.Errorf("Expected custom model to be registered, got %+v", custom)
+	}
+}
+
+func TestRegistryLoadInvalid(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.LoadJSON(strings.NewReader(`[{"name": "ok"}, {"contextWindow": 1}]`)); err == nil {
+		t.Error("Expected error for entry without name")
+	}
+	if _, ok := r.Lookup("ok"); ok {
+		t.Error("Expected failed load to leave registry untouched")
+	}
+}
+
+func TestRegistryLoadFileYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	content := `
+- name: yandexgpt-lite
+  maxOutputTokens: 4000
+  pricing:
+    currency: RUB
+    syncPer1K: 0.25
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRegistry(ModelInfo{Name: YandexGPTLite, ContextWindow: 32768})
+	if err := r.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := r.Lookup(YandexGPTLite)
+	if info.MaxOutputTokens != 4000 || info.Pricing.SyncPer1K != 0.25 || info.ContextWindow != 32768 {
+		t.Errorf("Unexpected model after YAML load: %+v", info)
+	}
+
+	if err := r.LoadFile(filepath.Join(t.TempDir(), "models.toml")); err == nil {
+		t.Error("Expected error for unsupported file")
+	}
+}
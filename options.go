@@ -0,0 +1,149 @@
+package yandexgpt
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// HeaderDataLoggingEnabled controls whether Yandex Cloud may log the
+	// request and response for service improvement.
+	HeaderDataLoggingEnabled = "x-data-logging-enabled"
+	// HeaderIdempotencyKey makes the server treat repeated requests with the
+	// same key as one.
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// ClientOption configures a Client at construction time.
+type ClientOption func(*Client)
+
+// WithDefaultHeaders adds headers to every API request made by the client.
+// Per-call headers set with WithHeader take precedence.
+func WithDefaultHeaders(header http.Header) ClientOption {
+	return func(c *Client) {
+		c.defaultCallOptions = append(c.defaultCallOptions, WithHeaders(header))
+	}
+}
+
+// WithDefaultCallOptions applies opts to every call made by the client,
+// before the options passed to the call itself:
+//
+//	client, err := yandexgpt.NewClient(token, folderID,
+//	    yandexgpt.WithDefaultCallOptions(yandexgpt.WithDataLoggingDisabled()))
+func WithDefaultCallOptions(opts ...CallOption) ClientOption {
+	return func(c *Client) {
+		c.defaultCallOptions = append(c.defaultCallOptions, opts...)
+	}
+}
+
+// CallOption configures a single API call. Every client method accepts
+// call options as trailing arguments.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	header         http.Header
+	timeout        time.Duration
+	folderID       string
+	retry          *RetryPolicy
+	idempotencyKey string
+	tags           map[string]string
+	fallback       FallbackPolicy
+	hedge          *HedgePolicy
+	cache          cacheMode
+	noCoalescing   bool
+	pollInterval   time.Duration
+}
+
+// WithHeader sets an HTTP header on the request.
+func WithHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		o.header.Set(key, value)
+	}
+}
+
+// WithHeaders sets every header in header on the request, replacing the
+// values of headers set earlier.
+func WithHeaders(header http.Header) CallOption {
+	header = header.Clone()
+	return func(o *callOptions) {
+		for key, values := range header {
+			o.header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+}
+
+// WithDataLoggingDisabled asks Yandex Cloud not to log the request and the
+// response by sending "x-data-logging-enabled: false".
+func WithDataLoggingDisabled() CallOption {
+	return WithHeader(HeaderDataLoggingEnabled, "false")
+}
+
+// WithTimeout limits the duration of the call, including retries and, for
+// GenerateImage, polling.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// DefaultPollInterval is the delay between polls of the operation of
+// GenerateImage.
+const DefaultPollInterval = 2 * time.Second
+
+// WithPollInterval sets the delay between polls of the operation of
+// GenerateImage. Non-positive values select DefaultPollInterval.
+func WithPollInterval(interval time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithFolder runs the call in another folder than the client's, without
+// changing the client.
+func WithFolder(folderID string) CallOption {
+	return func(o *callOptions) {
+		o.folderID = folderID
+	}
+}
+
+// WithRetryPolicy replaces the retry policy of the call. Pass RetryPolicy{}
+// to disable retries.
+func WithRetryPolicy(policy RetryPolicy) CallOption {
+	return func(o *callOptions) {
+		o.retry = &policy
+	}
+}
+
+// WithIdempotencyKey sends key as the Idempotency-Key header of every
+// attempt, so retries of the call are not executed twice by the server.
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// WithTags attaches metadata tags to the call. Tags are not sent to the API;
+// they are reported in ResponseMetadata.Tags of the result or the error.
+func WithTags(tags map[string]string) CallOption {
+	return func(o *callOptions) {
+		if o.tags == nil {
+			o.tags = make(map[string]string, len(tags))
+		}
+		for k, v := range tags {
+			o.tags[k] = v
+		}
+	}
+}
+
+// newCallOptions combines the client defaults with the options of a call.
+// The result is private to the call, so concurrent calls never share state.
+func (c *Client) newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{header: http.Header{}, folderID: c.folderID}
+	for _, opt := range c.defaultCallOptions {
+		opt(o)
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
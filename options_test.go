@@ -0,0 +1,269 @@
+package yandexgpt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestCallOptionHeaders(t *testing.T) {
+	var header http.Header
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+	client.defaultCallOptions = []CallOption{
+		WithHeaders(http.Header{"X-Tenant": []string{"default"}}),
+		WithHeader("X-Team", "search"),
+	}
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		WithDataLoggingDisabled(),
+		WithHeader("X-Tenant", "acme"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := header.Get(HeaderDataLoggingEnabled); got != "false" {
+		t.Errorf("Expected %s: false, got %q", HeaderDataLoggingEnabled, got)
+	}
+	if got := header.Get("X-Tenant"); got != "acme" {
+		t.Errorf("Expected per-call header to override the default, got %q", got)
+	}
+	if got := header.Get("X-Team"); got != "search" {
+		t.Errorf("Expected default header, got %q", got)
+	}
+	if got := header.Get("Authorization"); got != "Bearer test_iam_token" {
+		t.Errorf("Expected authorization header to be kept, got %q", got)
+	}
+}
+
+func TestDefaultHeadersClientOption(t *testing.T) {
+	client, err := NewClient("test_token", "test_folder",
+		WithDefaultHeaders(http.Header{"X-Data-Logging-Enabled": []string{"false"}}),
+		WithDefaultCallOptions(WithHeader("X-Team", "search")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := client.newCallOptions([]CallOption{WithHeader("X-Team", "ads")})
+	if got := call.header.Get(HeaderDataLoggingEnabled); got != "false" {
+		t.Errorf("Expected default data logging header, got %q", got)
+	}
+	if got := call.header.Get("X-Team"); got != "ads" {
+		t.Errorf("Expected per-call header to win, got %q", got)
+	}
+}
+
+func TestCallOptionsApplyToOperationsAndConversations(t *testing.T) {
+	var seen []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get(HeaderDataLoggingEnabled))
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "op-1", "done": false})
+	})
+
+	origURL := conversationsBaseURL
+	defer setConversationsBaseURL(origURL)
+	setConversationsBaseURL("https://ai.api.cloud.yandex.net/v1/conversations")
+
+	if _, err := client.GetOperation("op-1", WithDataLoggingDisabled()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GenerateImageAsync("cat", nil, nil, WithDataLoggingDisabled()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Conversations().Get("conv-1", WithDataLoggingDisabled()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(seen))
+	}
+	for i, v := range seen {
+		if v != "false" {
+			t.Errorf("Request %d: expected data logging disabled, got %q", i, v)
+		}
+	}
+}
+
+func TestWithFolder(t *testing.T) {
+	var modelURI string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		modelURI = req.ModelURI
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil, WithFolder("other_folder")); err != nil {
+		t.Fatal(err)
+	}
+	if modelURI != "gpt://other_folder/yandexgpt-lite" {
+		t.Errorf("Expected model URI in other_folder, got %s", modelURI)
+	}
+	if client.GetFolderID() != "test_folder" {
+		t.Errorf("Expected client folder to stay test_folder, got %s", client.GetFolderID())
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	var attempts int
+	var keys, ids []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		keys = append(keys, r.Header.Get(HeaderIdempotencyKey))
+		ids = append(ids, r.Header.Get(HeaderClientRequestID))
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		WithIdempotencyKey("key-1"),
+		WithTags(map[string]string{"feature": "chat"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", attempts)
+	}
+	if keys[0] != "key-1" || keys[1] != "key-1" {
+		t.Errorf("Expected the idempotency key on every attempt, got %v", keys)
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("Expected the same client request ID on every attempt, got %v", ids)
+	}
+
+	metadata := response.ResponseMetadata()
+	if metadata.Attempts != 2 {
+		t.Errorf("Expected Attempts 2, got %d", metadata.Attempts)
+	}
+	if metadata.Tags["feature"] != "chat" {
+		t.Errorf("Expected tags in metadata, got %v", metadata.Tags)
+	}
+}
+
+func TestRetryPolicyStopsOnPermanentError(t *testing.T) {
+	var attempts int
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"grpcCode":3,"message":"bad request"}}`))
+	})
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	)
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Expected ErrInvalidArgument, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+	if metadata := ErrorMetadata(err); metadata == nil || metadata.Attempts != 1 {
+		t.Errorf("Expected metadata with one attempt, got %+v", metadata)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	if d := policy.backoff(1, nil); d != 100*time.Millisecond {
+		t.Errorf("Expected 100ms, got %v", d)
+	}
+	if d := policy.backoff(2, nil); d != 200*time.Millisecond {
+		t.Errorf("Expected 200ms, got %v", d)
+	}
+	if d := policy.backoff(5, nil); d != 300*time.Millisecond {
+		t.Errorf("Expected backoff capped at 300ms, got %v", d)
+	}
+
+	rateErr := &RateLimitError{APIError: NewAPIError("slow down", 429, nil), RetryAfter: time.Second}
+	if d := policy.backoff(1, rateErr); d != time.Second {
+		t.Errorf("Expected Retry-After to win, got %v", d)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode(CompletionResponse{})
+	})
+	t.Cleanup(func() { close(release) })
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil, WithTimeout(20*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestConcurrentCallOptions(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(CompletionResponse{Result: Result{
+			Alternatives: []Alternative{{Message: Message{Text: req.ModelURI}}},
+		}})
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			folder := fmt.Sprintf("folder_%d", i)
+			response, err := client.GenerateText("Hello", models.YandexGPTLite, nil, WithFolder(folder))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got := response.Result.Alternatives[0].Message.Text; got != "gpt://"+folder+"/yandexgpt-lite" {
+				t.Errorf("Expected %s, got %s", folder, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestWithPollInterval(t *testing.T) {
+	var polls int32
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(`{"id":"op1","done":false}`))
+			return
+		}
+		if atomic.AddInt32(&polls, 1) < 3 {
+			w.Write([]byte(`{"id":"op1","done":false}`))
+			return
+		}
+		w.Write([]byte(`{"id":"op1","done":true,"response":{"image":"aW1n"}}`))
+	})
+
+	start := time.Now()
+	result, err := client.GenerateImage("cat", nil, nil, WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ImageBase64 != "aW1n" || polls != 3 {
+		t.Errorf("Unexpected result %+v after %d polls", result, polls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected fast polling, took %v", elapsed)
+	}
+}
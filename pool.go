@@ -0,0 +1,255 @@
+package yandexgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Generator generates text completions. It is implemented by *Client and
+// *Pool, so code written against it works with either.
+type Generator interface {
+	GenerateTextContext(ctx context.Context, prompt, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error)
+	GenerateFromMessagesContext(ctx context.Context, messages []Message, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error)
+}
+
+var (
+	_ Generator = (*Client)(nil)
+	_ Generator = (*Pool)(nil)
+)
+
+// BalanceStrategy selects the pool member that serves a request.
+type BalanceStrategy int
+
+const (
+	// RoundRobin cycles through the available members.
+	RoundRobin BalanceStrategy = iota
+	// LeastLoaded picks the available member with the fewest requests in flight.
+	LeastLoaded
+)
+
+// DefaultPoolCooldown is how long a member is ejected after a rate limit or
+// quota error that does not say when to retry.
+const DefaultPoolCooldown = 30 * time.Second
+
+// PoolOptions configures a Pool. The zero value is usable.
+type PoolOptions struct {
+	Strategy BalanceStrategy
+	// Cooldown is how long a member is ejected after a RateLimitError or a
+	// QuotaExceededError. A longer Retry-After sent by the server wins.
+	// Defaults to DefaultPoolCooldown.
+	Cooldown time.Duration
+}
+
+// Pool spreads completion requests over several clients, typically for
+// different folders or service accounts, to combine their quotas.
+//
+// A member that returns a rate limit or quota error is ejected for a cooldown
+// period and the request is retried on the next available member. When every
+// member is ejected, the pool returns a RateLimitError whose RetryAfter is
+// the time until the first member becomes available again.
+type Pool struct {
+	members  []*poolMember
+	strategy BalanceStrategy
+	cooldown time.Duration
+	next     atomic.Uint64
+	now      func() time.Time
+}
+
+type poolMember struct {
+	client   *Client
+	inFlight atomic.Int64
+
+	mu           sync.Mutex
+	ejectedUntil time.Time
+	requests     int64
+	failures     int64
+	rateLimited  int64
+	usage        Usage
+}
+
+// PoolMemberStats reports the health and usage of a pool member.
+type PoolMemberStats struct {
+	// FolderID is the folder of the member's client.
+	FolderID string
+	// Healthy is false while the member is ejected.
+	Healthy      bool
+	EjectedUntil time.Time
+	InFlight     int
+	// Requests counts completed requests, successful or not.
+	Requests int64
+	Failures int64
+	// RateLimited counts rate limit and quota errors.
+	RateLimited int64
+	// Usage is the sum of the usage of successful requests.
+	Usage Usage
+}
+
+// NewPool returns a pool over clients.
+func NewPool(clients []*Client, opts *PoolOptions) (*Pool, error) {
+	if len(clients) == 0 {
+		return nil, newInvalidArgumentError("pool needs at least one client", nil)
+	}
+
+	p := &Pool{
+		cooldown: DefaultPoolCooldown,
+		now:      time.Now,
+	}
+	if opts != nil {
+		p.strategy = opts.Strategy
+		if opts.Cooldown > 0 {
+			p.cooldown = opts.Cooldown
+		}
+	}
+
+	for _, c := range clients {
+		if c == nil {
+			return nil, newInvalidArgumentError("pool client cannot be nil", nil)
+		}
+		p.members = append(p.members, &poolMember{client: c})
+	}
+	return p, nil
+}
+
+func (p *Pool) GenerateText(prompt, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return p.GenerateTextContext(context.Background(), prompt, model, options, opts...)
+}
+
+// GenerateTextContext is like Client.GenerateTextContext, served by a pool member.
+func (p *Pool) GenerateTextContext(ctx context.Context, prompt, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return p.do(ctx, func(c *Client) (*CompletionResponse, error) {
+		return c.GenerateTextContext(ctx, prompt, model, options, opts...)
+	})
+}
+
+func (p *Pool) GenerateFromMessages(messages []Message, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return p.GenerateFromMessagesContext(context.Background(), messages, model, options, opts...)
+}
+
+// GenerateFromMessagesContext is like Client.GenerateFromMessagesContext,
+// served by a pool member.
+func (p *Pool) GenerateFromMessagesContext(ctx context.Context, messages []Message, model string, options *CompletionOptions, opts ...CallOption) (*CompletionResponse, error) {
+	return p.do(ctx, func(c *Client) (*CompletionResponse, error) {
+		return c.GenerateFromMessagesContext(ctx, messages, model, options, opts...)
+	})
+}
+
+// Stats reports the health and usage of every member, in the order the
+// clients were passed to NewPool.
+func (p *Pool) Stats() []PoolMemberStats {
+	now := p.now()
+	stats := make([]PoolMemberStats, len(p.members))
+	for i, m := range p.members {
+		m.mu.Lock()
+		stats[i] = PoolMemberStats{
+			FolderID:     m.client.GetFolderID(),
+			Healthy:      !now.Before(m.ejectedUntil),
+			EjectedUntil: m.ejectedUntil,
+			InFlight:     int(m.inFlight.Load()),
+			Requests:     m.requests,
+			Failures:     m.failures,
+			RateLimited:  m.rateLimited,
+			Usage:        m.usage,
+		}
+		m.mu.Unlock()
+	}
+	return stats
+}
+
+func (p *Pool) do(ctx context.Context, call func(*Client) (*CompletionResponse, error)) (*CompletionResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt < len(p.members); attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		m, retryAfter := p.pick()
+		if m == nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, &RateLimitError{
+				APIError:   NewAPIError("all pool members are cooling down", http.StatusTooManyRequests, nil),
+				RetryAfter: retryAfter,
+			}
+		}
+
+		m.inFlight.Add(1)
+		response, err := call(m.client)
+		m.inFlight.Add(-1)
+
+		if !p.record(m, response, err) {
+			return response, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// pick returns an available member, or nil and the time until the first
+// ejected member becomes available.
+func (p *Pool) pick() (*poolMember, time.Duration) {
+	now := p.now()
+	n := len(p.members)
+	start := int(p.next.Add(1)-1) % n
+
+	var best *poolMember
+	var bestLoad int64
+	var soonest time.Time
+
+	for i := 0; i < n; i++ {
+		m := p.members[(start+i)%n]
+
+		m.mu.Lock()
+		until := m.ejectedUntil
+		m.mu.Unlock()
+
+		if now.Before(until) {
+			if soonest.IsZero() || until.Before(soonest) {
+				soonest = until
+			}
+			continue
+		}
+		if p.strategy == RoundRobin {
+			return m, 0
+		}
+		if load := m.inFlight.Load(); best == nil || load < bestLoad {
+			best, bestLoad = m, load
+		}
+	}
+
+	if best != nil {
+		return best, 0
+	}
+	return nil, soonest.Sub(now)
+}
+
+// record updates the member statistics and reports whether the request
+// should be retried on another member.
+func (p *Pool) record(m *poolMember, response *CompletionResponse, err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	if err == nil {
+		m.usage = m.usage.Add(response.Result.Usage)
+		return false
+	}
+	m.failures++
+
+	if !errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrQuotaExceeded) {
+		return false
+	}
+	m.rateLimited++
+
+	cooldown := p.cooldown
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) && rateErr.RetryAfter > cooldown {
+		cooldown = rateErr.RetryAfter
+	}
+	m.ejectedUntil = p.now().Add(cooldown)
+	return true
+}
@@ -0,0 +1,179 @@
+package yandexgpt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+// setupPoolTestServer returns clients for the given folders served by
+// handler, which receives the folder of every completion request.
+func setupPoolTestServer(t *testing.T, folders []string, handler func(folder string, w http.ResponseWriter)) []*Client {
+	t.Helper()
+
+	base := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		folder := strings.Split(strings.TrimPrefix(req.ModelURI, "gpt://"), "/")[0]
+		handler(folder, w)
+	})
+
+	clients := make([]*Client, len(folders))
+	for i, folder := range folders {
+		clients[i] = base.WithFolder(folder)
+	}
+	return clients
+}
+
+func writeCompletion(w http.ResponseWriter, text string) {
+	json.NewEncoder(w).Encode(CompletionResponse{Result: Result{
+		Alternatives: []Alternative{{Message: Message{Role: "assistant", Text: text}}},
+		Usage:        Usage{InputTextTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+	}})
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	var mu sync.Mutex
+	served := map[string]int{}
+	clients := setupPoolTestServer(t, []string{"folder_a", "folder_b"}, func(folder string, w http.ResponseWriter) {
+		mu.Lock()
+		served[folder]++
+		mu.Unlock()
+		writeCompletion(w, folder)
+	})
+
+	pool, err := NewPool(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := pool.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if served["folder_a"] != 2 || served["folder_b"] != 2 {
+		t.Errorf("Expected requests to alternate, got %v", served)
+	}
+
+	stats := pool.Stats()
+	if stats[0].Requests != 2 || stats[0].Usage.TotalTokens != 6 || !stats[0].Healthy {
+		t.Errorf("Unexpected stats for folder_a: %+v", stats[0])
+	}
+}
+
+func TestPoolEjectsRateLimitedMember(t *testing.T) {
+	clients := setupPoolTestServer(t, []string{"folder_a", "folder_b"}, func(folder string, w http.ResponseWriter) {
+		if folder == "folder_a" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"grpcCode":8,"httpCode":429,"message":"rate limit"}}`))
+			return
+		}
+		writeCompletion(w, folder)
+	})
+
+	pool, _ := NewPool(clients, &PoolOptions{Cooldown: time.Minute})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		response, err := pool.GenerateText("Hello", models.YandexGPTLite, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := response.Result.Alternatives[0].Message.Text; text != "folder_b" {
+			t.Errorf("Expected folder_b to serve the request, got %s", text)
+		}
+	}
+
+	stats := pool.Stats()
+	if stats[0].Healthy || stats[0].RateLimited != 1 || !stats[0].EjectedUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected folder_a to be ejected once, got %+v", stats[0])
+	}
+	if !stats[1].Healthy || stats[1].Requests != 3 {
+		t.Errorf("Expected folder_b to serve every request, got %+v", stats[1])
+	}
+
+	// After the cooldown folder_a is tried again.
+	now = now.Add(2 * time.Minute)
+	if !pool.Stats()[0].Healthy {
+		t.Error("Expected folder_a to be healthy after the cooldown")
+	}
+}
+
+func TestPoolAllMembersCoolingDown(t *testing.T) {
+	clients := setupPoolTestServer(t, []string{"folder_a", "folder_b"}, func(folder string, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"grpcCode":8,"httpCode":429,"message":"rate limit"}}`))
+	})
+
+	pool, _ := NewPool(clients, &PoolOptions{Cooldown: time.Minute})
+
+	_, err := pool.GenerateText("Hello", models.YandexGPTLite, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected rate limit error, got %v", err)
+	}
+
+	_, err = pool.GenerateText("Hello", models.YandexGPTLite, nil)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected RateLimitError, got %T", err)
+	}
+	if rateErr.RetryAfter <= 0 || rateErr.RetryAfter > time.Minute {
+		t.Errorf("Expected RetryAfter within the cooldown, got %v", rateErr.RetryAfter)
+	}
+}
+
+func TestPoolLeastLoaded(t *testing.T) {
+	clients := setupPoolTestServer(t, []string{"folder_a", "folder_b"}, func(folder string, w http.ResponseWriter) {
+		writeCompletion(w, folder)
+	})
+
+	pool, _ := NewPool(clients, &PoolOptions{Strategy: LeastLoaded})
+	pool.members[0].inFlight.Store(5)
+
+	for i := 0; i < 3; i++ {
+		response, err := pool.GenerateText("Hello", models.YandexGPTLite, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := response.Result.Alternatives[0].Message.Text; text != "folder_b" {
+			t.Errorf("Expected the least loaded member folder_b, got %s", text)
+		}
+	}
+}
+
+func TestPoolDoesNotRetryOtherErrors(t *testing.T) {
+	var calls int
+	clients := setupPoolTestServer(t, []string{"folder_a", "folder_b"}, func(folder string, w http.ResponseWriter) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"grpcCode":3,"message":"bad request"}}`))
+	})
+
+	pool, _ := NewPool(clients, nil)
+	if _, err := pool.GenerateText("Hello", models.YandexGPTLite, nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single request, got %d", calls)
+	}
+	if !pool.Stats()[0].Healthy || !pool.Stats()[1].Healthy {
+		t.Error("Expected no member to be ejected")
+	}
+}
+
+func TestNewPoolValidation(t *testing.T) {
+	if _, err := NewPool(nil, nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for an empty pool, got %v", err)
+	}
+	if _, err := NewPool([]*Client{nil}, nil); err == nil {
+		t.Error("Expected error for a nil client")
+	}
+}
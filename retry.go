@@ -0,0 +1,91 @@
+package yandexgpt
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. The zero value
+// disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every retry. Defaults to 2.
+	Multiplier float64
+	// RetryOn reports whether err is worth retrying. Defaults to IsRetryable.
+	RetryOn func(err error) bool
+}
+
+// DefaultRetryPolicy retries transient failures twice with exponential backoff.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+}
+
+// IsRetryable reports whether err is a transient failure: a rate limit, an
+// unavailable service or a request that got no response at all.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == 0 && apiErr.Metadata != nil && apiErr.Metadata.StatusCode == 0
+}
+
+func (p *RetryPolicy) shouldRetry(err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.RetryOn != nil {
+		return p.RetryOn(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff returns the delay before the given retry, starting at 1. A longer
+// Retry-After requested by the server wins.
+func (p *RetryPolicy) backoff(retry int, err error) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := time.Duration(float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1)))
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) && rateErr.RetryAfter > delay {
+		delay = rateErr.RetryAfter
+	}
+	return delay
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
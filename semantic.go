@@ -0,0 +1,331 @@
+package yandexgpt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Embedder turns text into an embedding vector.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface.
+type EmbedderFunc func(ctx context.Context, text string) ([]float64, error)
+
+// Embed calls f.
+func (f EmbedderFunc) Embed(ctx context.Context, text string) ([]float64, error) {
+	return f(ctx, text)
+}
+
+// Embedder returns an Embedder that embeds text with model through c, such
+// as models.TextSearchQuery.
+func (c *Client) Embedder(model string, opts ...CallOption) Embedder {
+	return EmbedderFunc(func(ctx context.Context, text string) ([]float64, error) {
+		response, err := c.EmbedContext(ctx, text, model, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return response.Embedding, nil
+	})
+}
+
+// VectorEntry is an entry of a VectorIndex.
+type VectorEntry struct {
+	Scope  string
+	Vector []float64
+	Value  []byte
+	// ExpiresAt is zero for entries without expiry.
+	ExpiresAt time.Time
+}
+
+// VectorIndex finds stored vectors by cosine similarity. Implementations
+// must be safe for concurrent use; adapters for vector databases can be
+// plugged into SemanticCacheOptions.Index.
+type VectorIndex interface {
+	// Nearest returns the unexpired entry of scope most similar to vector
+	// and their cosine similarity. It returns false if scope has no entry.
+	Nearest(ctx context.Context, scope string, vector []float64) (VectorEntry, float64, bool, error)
+	Add(ctx context.Context, entry VectorEntry) error
+	// Delete removes the entries of scope, or every entry if scope is empty.
+	Delete(ctx context.Context, scope string) error
+}
+
+// DefaultSemanticThreshold is the cosine similarity above which a prompt is
+// answered from the semantic cache unless SemanticCacheOptions.Threshold is
+// set.
+const DefaultSemanticThreshold = 0.92
+
+// SemanticCacheOptions configures a SemanticCache. The zero value is usable.
+type SemanticCacheOptions struct {
+	// Threshold defaults to DefaultSemanticThreshold.
+	Threshold float64
+	// TTL is the lifetime of cached responses. Zero means no expiry.
+	TTL time.Duration
+	// Index defaults to NewMemoryVectorIndex(0).
+	Index VectorIndex
+	// Cacheable reports whether the response to request may be cached and
+	// served from the cache. By default every eligible completion is.
+	Cacheable func(request *CompletionRequest) bool
+}
+
+// SemanticCache answers prompts that are similar, not only identical, to
+// earlier ones: "what is your refund policy" and "how do refunds work" get
+// the same response. Prompts are embedded and looked up in a vector index
+// within their scope, which is the model URI and the system prompt.
+//
+// Only single-turn completions are eligible: one user message, optionally
+// preceded by system messages. Completion options are not part of the
+// scope, so use one cache per kind of workload.
+type SemanticCache struct {
+	embedder  Embedder
+	index     VectorIndex
+	threshold float64
+	ttl       time.Duration
+	cacheable func(*CompletionRequest) bool
+}
+
+// NewSemanticCache returns a semantic cache embedding prompts with embedder,
+// usually client.Embedder(models.TextSearchQuery). opts may be nil.
+func NewSemanticCache(embedder Embedder, opts *SemanticCacheOptions) *SemanticCache {
+	s := &SemanticCache{embedder: embedder, threshold: DefaultSemanticThreshold}
+	if opts != nil {
+		if opts.Threshold > 0 {
+			s.threshold = opts.Threshold
+		}
+		s.ttl = opts.TTL
+		s.index = opts.Index
+		s.cacheable = opts.Cacheable
+	}
+	if s.index == nil {
+		s.index = NewMemoryVectorIndex(0)
+	}
+	return s
+}
+
+// WithSemanticCache answers completions from cache. It is consulted after
+// the exact cache of WithCache, if any, and obeys WithCacheBypass,
+// WithCacheRefresh and the CachePolicy.Cacheable of WithCache. Hits report ResponseMetadata.Cached and lookups are
+// reported to Hooks.OnCacheLookup with CacheEvent.Semantic set.
+func WithSemanticCache(cache *SemanticCache) ClientOption {
+	return func(c *Client) {
+		c.semanticCache = cache
+	}
+}
+
+// SemanticScope returns the scope of the semantic cache entries of requests
+// to modelURI with systemPrompt. System messages are joined by newlines.
+func SemanticScope(modelURI, systemPrompt string) string {
+	sum := sha256.Sum256([]byte(systemPrompt))
+	return modelURI + "#" + hex.EncodeToString(sum[:8])
+}
+
+// Invalidate removes the cached responses of requests to modelURI, such as
+// "gpt://b1gfolder/yandexgpt-lite", with systemPrompt.
+func (s *SemanticCache) Invalidate(ctx context.Context, modelURI, systemPrompt string) error {
+	return s.index.Delete(ctx, SemanticScope(modelURI, systemPrompt))
+}
+
+// InvalidateAll removes every cached response.
+func (s *SemanticCache) InvalidateAll(ctx context.Context) error {
+	return s.index.Delete(ctx, "")
+}
+
+// semanticQuery is an eligible request with its embedded prompt.
+type semanticQuery struct {
+	scope  string
+	vector []float64
+}
+
+// query embeds the prompt of request. It returns nil if request is not
+// eligible.
+func (s *SemanticCache) query(ctx context.Context, request *CompletionRequest) (*semanticQuery, error) {
+	if s == nil || (s.cacheable != nil && !s.cacheable(request)) {
+		return nil, nil
+	}
+	var system []string
+	var prompt string
+	for i, m := range request.Messages {
+		switch {
+		case m.Role == "system" && prompt == "":
+			system = append(system, m.Text)
+		case m.Role == "user" && i == len(request.Messages)-1:
+			prompt = m.Text
+		default:
+			return nil, nil
+		}
+	}
+	if prompt == "" {
+		return nil, nil
+	}
+
+	vector, err := s.embedder.Embed(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &semanticQuery{scope: SemanticScope(request.ModelURI, strings.Join(system, "\n")), vector: vector}, nil
+}
+
+func (s *SemanticCache) lookup(ctx context.Context, q *semanticQuery) (*CompletionResponse, float64, error) {
+	entry, similarity, ok, err := s.index.Nearest(ctx, q.scope, q.vector)
+	if err != nil || !ok || similarity < s.threshold {
+		return nil, similarity, err
+	}
+	var response CompletionResponse
+	if err := json.Unmarshal(entry.Value, &response); err != nil {
+		return nil, similarity, err
+	}
+	return &response, similarity, nil
+}
+
+func (s *SemanticCache) store(ctx context.Context, q *semanticQuery, response *CompletionResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	entry := VectorEntry{Scope: q.scope, Vector: q.vector, Value: data}
+	if s.ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(s.ttl)
+	}
+	s.index.Add(ctx, entry)
+}
+
+// MemoryVectorIndex is an in-memory VectorIndex searched exhaustively,
+// which suits caches of up to tens of thousands of entries.
+type MemoryVectorIndex struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	scopes  map[string][]*memoryVector
+	entries int
+	seq     uint64
+}
+
+type memoryVector struct {
+	VectorEntry
+	norm float64
+	seq  uint64
+}
+
+// NewMemoryVectorIndex returns an index holding up to maxEntries entries,
+// or any number if maxEntries is not positive. The oldest entry is dropped
+// to make room for a new one.
+func NewMemoryVectorIndex(maxEntries int) *MemoryVectorIndex {
+	return &MemoryVectorIndex{maxEntries: maxEntries, scopes: map[string][]*memoryVector{}}
+}
+
+// Nearest implements VectorIndex. Expired entries are dropped.
+func (m *MemoryVectorIndex) Nearest(ctx context.Context, scope string, vector []float64) (VectorEntry, float64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	norm := vectorNorm(vector)
+	var best *memoryVector
+	bestSimilarity := math.Inf(-1)
+	kept := m.scopes[scope][:0]
+	for _, v := range m.scopes[scope] {
+		if !v.ExpiresAt.IsZero() && now.After(v.ExpiresAt) {
+			m.entries--
+			continue
+		}
+		kept = append(kept, v)
+		if similarity := cosineSimilarity(vector, norm, v.Vector, v.norm); similarity > bestSimilarity {
+			best, bestSimilarity = v, similarity
+		}
+	}
+	m.setScope(scope, kept)
+
+	if best == nil {
+		return VectorEntry{}, 0, false, nil
+	}
+	return best.VectorEntry, bestSimilarity, true, nil
+}
+
+// Add implements VectorIndex.
+func (m *MemoryVectorIndex) Add(ctx context.Context, entry VectorEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.maxEntries > 0 && m.entries >= m.maxEntries {
+		m.evictOldest()
+	}
+	m.seq++
+	m.scopes[entry.Scope] = append(m.scopes[entry.Scope], &memoryVector{VectorEntry: entry, norm: vectorNorm(entry.Vector), seq: m.seq})
+	m.entries++
+	return nil
+}
+
+// Delete implements VectorIndex.
+func (m *MemoryVectorIndex) Delete(ctx context.Context, scope string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if scope == "" {
+		m.scopes = map[string][]*memoryVector{}
+		m.entries = 0
+		return nil
+	}
+	m.entries -= len(m.scopes[scope])
+	delete(m.scopes, scope)
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet dropped.
+func (m *MemoryVectorIndex) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries
+}
+
+func (m *MemoryVectorIndex) setScope(scope string, vectors []*memoryVector) {
+	if len(vectors) == 0 {
+		delete(m.scopes, scope)
+		return
+	}
+	m.scopes[scope] = vectors
+}
+
+// evictOldest drops the entry added first. Entries of a scope are kept in
+// insertion order, so it is the first entry of some scope.
+func (m *MemoryVectorIndex) evictOldest() {
+	var oldest string
+	var seq uint64
+	for scope, vectors := range m.scopes {
+		if seq == 0 || vectors[0].seq < seq {
+			oldest, seq = scope, vectors[0].seq
+		}
+	}
+	if seq == 0 {
+		return
+	}
+	m.setScope(oldest, m.scopes[oldest][1:])
+	m.entries--
+}
+
+func vectorNorm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+// cosineSimilarity returns the cosine similarity of a and b given their
+// norms, or 0 if they differ in length or either is zero.
+func cosineSimilarity(a []float64, normA float64, b []float64, normB float64) float64 {
+	if len(a) != len(b) || normA == 0 || normB == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot / (normA * normB)
+}
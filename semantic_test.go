@@ -0,0 +1,192 @@
+package yandexgpt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+var testEmbeddings = map[string][]float64{
+	"what is your refund policy": {1, 0, 0},
+	"how do refunds work":        {0.95, 0.1, 0},
+	"where is my order":          {0, 1, 0},
+}
+
+var testEmbedder = EmbedderFunc(func(ctx context.Context, text string) ([]float64, error) {
+	if vector, ok := testEmbeddings[text]; ok {
+		return vector, nil
+	}
+	return nil, errors.New("unknown text")
+})
+
+func TestSemanticCache(t *testing.T) {
+	var calls int32
+	var events []CacheEvent
+	cache := NewSemanticCache(testEmbedder, nil)
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, fmt.Sprintf("answer %d", atomic.AddInt32(&calls, 1)))
+	},
+		WithSemanticCache(cache),
+		WithHooks(Hooks{OnCacheLookup: func(e CacheEvent) { events = append(events, e) }}),
+	)
+
+	ask := func(system, prompt string, opts ...CallOption) *CompletionResponse {
+		t.Helper()
+		messages := []Message{{Role: "user", Text: prompt}}
+		if system != "" {
+			messages = append([]Message{{Role: "system", Text: system}}, messages...)
+		}
+		response, err := client.GenerateFromMessages(messages, models.YandexGPTLite, nil, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	text := func(r *CompletionResponse) string { return r.Result.Alternatives[0].Message.Text }
+
+	ask("You are a support bot", "what is your refund policy")
+	paraphrase := ask("You are a support bot", "how do refunds work")
+	if text(paraphrase) != "answer 1" || !paraphrase.ResponseMetadata().Cached {
+		t.Errorf("Expected the paraphrase to be served from the cache, got %q", text(paraphrase))
+	}
+	last := events[len(events)-1]
+	if !last.Semantic || !last.Hit || last.Similarity < 0.99 || last.Model != models.YandexGPTLite {
+		t.Errorf("Unexpected cache event: %+v", last)
+	}
+
+	if got := text(ask("You are a support bot", "where is my order")); got != "answer 2" {
+		t.Errorf("Expected a dissimilar prompt to reach the API, got %q", got)
+	}
+	if got := text(ask("You are a sales bot", "how do refunds work")); got != "answer 3" {
+		t.Errorf("Expected another system prompt to be another scope, got %q", got)
+	}
+	if got := text(ask("You are a support bot", "how do refunds work", WithCacheBypass())); got != "answer 4" {
+		t.Errorf("Expected a bypassed call to reach the API, got %q", got)
+	}
+
+	// Multi-turn conversations are not eligible.
+	_, err := client.GenerateFromMessages([]Message{
+		{Role: "user", Text: "what is your refund policy"},
+		{Role: "assistant", Text: "answer 1"},
+		{Role: "user", Text: "how do refunds work"},
+	}, models.YandexGPTLite, nil)
+	if err != nil || calls != 5 {
+		t.Errorf("Expected a multi-turn conversation to reach the API, got %v after %d calls", err, calls)
+	}
+
+	if err := cache.Invalidate(context.Background(), "gpt://test_folder/yandexgpt-lite", "You are a support bot"); err != nil {
+		t.Fatal(err)
+	}
+	if got := text(ask("You are a support bot", "how do refunds work")); got != "answer 6" {
+		t.Errorf("Expected the invalidated scope to reach the API, got %q", got)
+	}
+	if got := text(ask("You are a sales bot", "what is your refund policy")); got != "answer 3" {
+		t.Errorf("Expected other scopes to survive invalidation, got %q", got)
+	}
+
+	// Embedding failures are misses, not errors.
+	if got := text(ask("", "unknown prompt")); got != "answer 7" {
+		t.Errorf("Expected the call to reach the API, got %q", got)
+	}
+	if last := events[len(events)-1]; !last.Semantic || last.Err == nil {
+		t.Errorf("Expected the embedding error to be reported, got %+v", last)
+	}
+}
+
+func TestSemanticCacheWithExactCache(t *testing.T) {
+	var calls int32
+	var embeddings int32
+	embedder := EmbedderFunc(func(ctx context.Context, text string) ([]float64, error) {
+		atomic.AddInt32(&embeddings, 1)
+		return testEmbedder(ctx, text)
+	})
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, fmt.Sprintf("answer %d", atomic.AddInt32(&calls, 1)))
+	},
+		WithCache(NewMemoryCache(0), nil),
+		WithSemanticCache(NewSemanticCache(embedder, &SemanticCacheOptions{Threshold: 0.999})),
+	)
+
+	for _, prompt := range []string{"what is your refund policy", "what is your refund policy", "how do refunds work"} {
+		if _, err := client.GenerateText(prompt, models.YandexGPTLite, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 || embeddings != 2 {
+		t.Errorf("Expected the exact cache to answer first and the threshold to reject the paraphrase, got %d calls and %d embeddings", calls, embeddings)
+	}
+}
+
+func TestSemanticCacheObeysCachePolicy(t *testing.T) {
+	var calls, embeddings int32
+	embedder := EmbedderFunc(func(ctx context.Context, text string) ([]float64, error) {
+		atomic.AddInt32(&embeddings, 1)
+		return testEmbedder(ctx, text)
+	})
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, fmt.Sprintf("answer %d", atomic.AddInt32(&calls, 1)))
+	},
+		WithCache(NewMemoryCache(0), &CachePolicy{
+			Cacheable: func(r *CompletionRequest) bool { return r.CompletionOptions.Temperature <= 0.3 },
+		}),
+		WithSemanticCache(NewSemanticCache(embedder, nil)),
+	)
+
+	hot := &CompletionOptions{Temperature: 0.9, MaxTokens: 100}
+	for i := 0; i < 2; i++ {
+		response, err := client.GenerateText("what is your refund policy", models.YandexGPTLite, hot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.ResponseMetadata().Cached {
+			t.Error("Expected a request the policy does not cache not to be served from cache")
+		}
+	}
+	if calls != 2 || embeddings != 0 {
+		t.Errorf("Expected 2 upstream calls and no embeddings, got %d and %d", calls, embeddings)
+	}
+}
+
+func TestMemoryVectorIndex(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryVectorIndex(3)
+	index.Add(ctx, VectorEntry{Scope: "a", Vector: []float64{1, 0}, Value: []byte("a1")})
+	index.Add(ctx, VectorEntry{Scope: "b", Vector: []float64{1, 0}, Value: []byte("b1")})
+	index.Add(ctx, VectorEntry{Scope: "a", Vector: []float64{0, 1}, Value: []byte("a2")})
+
+	entry, similarity, ok, err := index.Nearest(ctx, "a", []float64{1, 1})
+	if err != nil || !ok || string(entry.Value) != "a1" || math.Abs(similarity-math.Sqrt2/2) > 1e-9 {
+		t.Errorf("Unexpected nearest entry %q with similarity %v", entry.Value, similarity)
+	}
+
+	// The oldest entry is dropped for a fourth one.
+	index.Add(ctx, VectorEntry{Scope: "b", Vector: []float64{0, 1}, Value: []byte("b2"), ExpiresAt: time.Now().Add(-time.Second)})
+	if entry, _, _, _ := index.Nearest(ctx, "a", []float64{1, 0}); string(entry.Value) != "a2" {
+		t.Errorf("Expected a1 to be evicted, got %q", entry.Value)
+	}
+	if entry, _, _, _ := index.Nearest(ctx, "b", []float64{0, 1}); string(entry.Value) != "b1" {
+		t.Errorf("Expected the expired entry to be skipped, got %q", entry.Value)
+	}
+	if index.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", index.Len())
+	}
+
+	index.Delete(ctx, "b")
+	if _, _, ok, _ := index.Nearest(ctx, "b", []float64{1, 0}); ok {
+		t.Error("Expected scope b to be deleted")
+	}
+	index.Delete(ctx, "")
+	if index.Len() != 0 {
+		t.Errorf("Expected an empty index, got %d entries", index.Len())
+	}
+}
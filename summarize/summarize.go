@@ -0,0 +1,423 @@
+// Package summarize condenses documents that do not fit into a single
+// YandexGPT request.
+//
+// Two strategies are available. MapReduce summarizes every chunk
+// independently and in parallel, then combines the partial summaries,
+// collapsing them in several rounds if they are still too long. Refine walks
+// the chunks in order and keeps improving a single running summary, which
+// preserves the narrative better at the cost of being sequential.
+//
+//	result, err := summarize.Summarize(ctx, client, longText, &summarize.Options{
+//	    MapModel:    models.YandexGPTLite,
+//	    ReduceModel: models.YandexGPT,
+//	    Concurrency: 8,
+//	})
+//	fmt.Println(result.Summary, result.Usage.TotalTokens)
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+	"github.com/tigusigalpa/yandexgpt-go/v2/textsplit"
+)
+
+// Generator is the part of *yandexgpt.Client used by Summarize.
+type Generator interface {
+	GenerateFromMessagesContext(ctx context.Context, messages []yandexgpt.Message, model string, options *yandexgpt.CompletionOptions, opts ...yandexgpt.CallOption) (*yandexgpt.CompletionResponse, error)
+}
+
+// Strategy selects how chunk summaries are combined.
+type Strategy string
+
+const (
+	// MapReduce summarizes chunks in parallel and then combines the summaries.
+	MapReduce Strategy = "map-reduce"
+	// Refine summarizes the first chunk and refines that summary with every following chunk.
+	Refine Strategy = "refine"
+)
+
+// Prompt placeholders. {text} is replaced with the chunk or the partial
+// summaries, {summary} with the running summary of the Refine strategy.
+const (
+	DefaultMapPrompt = "Кратко перескажи следующий текст, сохранив ключевые факты, имена и числа. " +
+		"Отвечай на языке исходного текста.\n\n{text}"
+	DefaultReducePrompt = "Ниже приведены краткие пересказы последовательных частей одного документа. " +
+		"Объедини их в один связный пересказ без повторов. Отвечай на языке исходного текста.\n\n{text}"
+	DefaultRefinePrompt = "Вот краткий пересказ начала документа:\n\n{summary}\n\n" +
+		"Дополни и уточни его с учётом следующей части документа. Верни только обновлённый пересказ " +
+		"на языке исходного текста.\n\n{text}"
+)
+
+// Defaults used when the corresponding Options field is zero.
+const (
+	DefaultChunkTokens        = 6000
+	DefaultRefineChunkTokens  = 3000
+	DefaultChunkOverlap       = 200
+	DefaultMaxReduceTokens    = 6000
+	DefaultConcurrency        = 4
+	defaultSummaryTemperature = 0.3
+	defaultSummaryMaxTokens   = 2000
+)
+
+// Options configures Summarize. The zero value is usable.
+type Options struct {
+	// Strategy defaults to MapReduce.
+	Strategy Strategy
+
+	// Splitter cuts the input into chunks. Defaults to a token splitter
+	// with DefaultChunkTokens per chunk, or DefaultRefineChunkTokens with
+	// the Refine strategy, measured by textsplit.EstimateCounter.
+	Splitter textsplit.Splitter
+
+	// MapModel summarizes individual chunks. Defaults to models.YandexGPTLite.
+	MapModel string
+	// ReduceModel combines summaries and refines the running summary.
+	// Defaults to models.YandexGPT.
+	ReduceModel string
+
+	// MapPrompt, ReducePrompt and RefinePrompt override the default prompts.
+	MapPrompt    string
+	ReducePrompt string
+	RefinePrompt string
+
+	// MapOptions and ReduceOptions are the completion options of each
+	// stage. They default to a low temperature and 2000 output tokens.
+	MapOptions    *yandexgpt.CompletionOptions
+	ReduceOptions *yandexgpt.CompletionOptions
+
+	// Concurrency limits parallel map requests. Defaults to DefaultConcurrency.
+	Concurrency int
+
+	// MaxReduceTokens is the largest estimated input of a single reduce or
+	// refine request, prompt included. Longer summary lists are collapsed in
+	// several rounds and longer summaries are split. A running summary
+	// taking more than half of it is collapsed, and a chunk that does not fit
+	// next to the running summary is refined in parts. Defaults to
+	// DefaultMaxReduceTokens.
+	MaxReduceTokens int
+
+	// CallOptions are passed to every completion request.
+	CallOptions []yandexgpt.CallOption
+}
+
+// Result is the outcome of Summarize.
+type Result struct {
+	Summary string
+	// Usage is the sum of the usage reported by every request made.
+	Usage yandexgpt.Usage
+	// Requests is the number of completion requests made.
+	Requests int
+	// Chunks is the number of chunks the input was split into.
+	Chunks int
+}
+
+// ErrEmptyInput is returned when there is nothing to summarize.
+var ErrEmptyInput = errors.New("summarize: input text is empty")
+
+// Summarize condenses text using client. It stops at the first failed
+// request and returns its error; pending map requests are cancelled.
+func Summarize(ctx context.Context, client Generator, text string, opts *Options) (*Result, error) {
+	o := withDefaults(opts)
+
+	chunks, err := o.Splitter.Split(text)
+	if err != nil {
+		return nil, fmt.Errorf("summarize: split text: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil, ErrEmptyInput
+	}
+
+	s := &summarizer{client: client, opts: o}
+
+	var summary string
+	switch o.Strategy {
+	case MapReduce:
+		summary, err = s.mapReduce(ctx, chunks)
+	case Refine:
+		summary, err = s.refine(ctx, chunks)
+	default:
+		return nil, fmt.Errorf("summarize: unknown strategy %q", o.Strategy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Summary:  summary,
+		Usage:    s.usage,
+		Requests: s.requests,
+		Chunks:   len(chunks),
+	}, nil
+}
+
+func withDefaults(opts *Options) Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Strategy == "" {
+		o.Strategy = MapReduce
+	}
+	if o.Splitter == nil {
+		chunkTokens := DefaultChunkTokens
+		if o.Strategy == Refine {
+			chunkTokens = DefaultRefineChunkTokens
+		}
+		o.Splitter = textsplit.NewTokenSplitter(textsplit.EstimateCounter, chunkTokens, DefaultChunkOverlap)
+	}
+	if o.MapModel == "" {
+		o.MapModel = models.YandexGPTLite
+	}
+	if o.ReduceModel == "" {
+		o.ReduceModel = models.YandexGPT
+	}
+	if o.MapPrompt == "" {
+		o.MapPrompt = DefaultMapPrompt
+	}
+	if o.ReducePrompt == "" {
+		o.ReducePrompt = DefaultReducePrompt
+	}
+	if o.RefinePrompt == "" {
+		o.RefinePrompt = DefaultRefinePrompt
+	}
+	if o.MapOptions == nil {
+		o.MapOptions = defaultCompletionOptions()
+	}
+	if o.ReduceOptions == nil {
+		o.ReduceOptions = defaultCompletionOptions()
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	if o.MaxReduceTokens <= 0 {
+		o.MaxReduceTokens = DefaultMaxReduceTokens
+	}
+	return o
+}
+
+func defaultCompletionOptions() *yandexgpt.CompletionOptions {
+	return &yandexgpt.CompletionOptions{
+		Temperature: defaultSummaryTemperature,
+		MaxTokens:   defaultSummaryMaxTokens,
+	}
+}
+
+type summarizer struct {
+	client Generator
+	opts   Options
+
+	mu       sync.Mutex
+	usage    yandexgpt.Usage
+	requests int
+}
+
+func (s *summarizer) mapReduce(ctx context.Context, chunks []string) (string, error) {
+	summaries, err := s.mapAll(ctx, chunks, s.opts.MapModel, s.opts.MapPrompt, s.opts.MapOptions)
+	if err != nil {
+		return "", err
+	}
+	return s.reduce(ctx, summaries)
+}
+
+// reduce combines summaries into one with reduce requests.
+func (s *summarizer) reduce(ctx context.Context, summaries []string) (string, error) {
+	budget, err := s.budget(s.opts.ReducePrompt, "ReducePrompt")
+	if err != nil {
+		return "", err
+	}
+	for {
+		groups, err := s.group(summaries, budget)
+		if err != nil {
+			return "", err
+		}
+		if len(groups) == 1 {
+			return s.generate(ctx, s.opts.ReduceModel, s.opts.ReduceOptions, s.opts.ReducePrompt, groups[0], "")
+		}
+		// Too long for one reduce request: collapse each group and try again.
+		collapsed, err := s.mapAll(ctx, groups, s.opts.ReduceModel, s.opts.ReducePrompt, s.opts.ReduceOptions)
+		if err != nil {
+			return "", err
+		}
+		// Rounds that do not shorten the summaries would never converge.
+		if estimateAll(collapsed) >= estimateAll(groups) {
+			return "", fmt.Errorf("summarize: reduce requests do not shorten the summaries, lower ReduceOptions.MaxTokens below MaxReduceTokens (%d)", s.opts.MaxReduceTokens)
+		}
+		summaries = collapsed
+	}
+}
+
+func (s *summarizer) refine(ctx context.Context, chunks []string) (string, error) {
+	budget, err := s.budget(s.opts.RefinePrompt, "RefinePrompt")
+	if err != nil {
+		return "", err
+	}
+	summary, err := s.generate(ctx, s.opts.MapModel, s.opts.MapOptions, s.opts.MapPrompt, chunks[0], "")
+	if err != nil {
+		return "", err
+	}
+	for _, chunk := range chunks[1:] {
+		// The running summary keeps at most half of the budget, and the chunk
+		// is refined in parts that fit into the rest.
+		if tokens := yandexgpt.EstimateTokens(summary); tokens > budget/2 && tokens+yandexgpt.EstimateTokens(chunk) > budget {
+			if summary, err = s.collapse(ctx, summary, budget/2); err != nil {
+				return "", err
+			}
+		}
+		room := budget - yandexgpt.EstimateTokens(summary)
+		parts := []string{chunk}
+		if yandexgpt.EstimateTokens(chunk) > room {
+			if parts, err = textsplit.NewTokenSplitter(textsplit.EstimateCounter, room, 0).Split(chunk); err != nil {
+				return "", fmt.Errorf("summarize: split chunk: %w", err)
+			}
+		}
+		for _, part := range parts {
+			if summary, err = s.generate(ctx, s.opts.ReduceModel, s.opts.ReduceOptions, s.opts.RefinePrompt, part, summary); err != nil {
+				return "", err
+			}
+		}
+	}
+	return summary, nil
+}
+
+// collapse shortens the running summary of the Refine strategy with reduce
+// requests until it is estimated at no more than limit tokens.
+func (s *summarizer) collapse(ctx context.Context, summary string, limit int) (string, error) {
+	for yandexgpt.EstimateTokens(summary) > limit {
+		shorter, err := s.reduce(ctx, []string{summary})
+		if err != nil {
+			return "", err
+		}
+		if yandexgpt.EstimateTokens(shorter) >= yandexgpt.EstimateTokens(summary) {
+			return "", fmt.Errorf("summarize: reduce requests do not shorten the running summary, lower ReduceOptions.MaxTokens below half of MaxReduceTokens (%d)", s.opts.MaxReduceTokens)
+		}
+		summary = shorter
+	}
+	return summary, nil
+}
+
+// budget returns the estimated tokens left for the text and the summary of
+// a request made with prompt, which must leave at least two.
+func (s *summarizer) budget(prompt, name string) (int, error) {
+	overhead := yandexgpt.EstimateTokens(strings.NewReplacer("{text}", "", "{summary}", "").Replace(prompt))
+	budget := s.opts.MaxReduceTokens - overhead
+	if budget < 2 {
+		return 0, fmt.Errorf("summarize: %s of about %d tokens leaves no room within MaxReduceTokens (%d)", name, overhead, s.opts.MaxReduceTokens)
+	}
+	return budget, nil
+}
+
+// group joins summaries into as few texts as possible, each estimated at no
+// more than budget tokens. Summaries too long on their own are split first.
+func (s *summarizer) group(summaries []string, budget int) ([]string, error) {
+	splitter := textsplit.NewTokenSplitter(textsplit.EstimateCounter, budget, 0)
+	var groups []string
+	var current []string
+	tokens := 0
+
+	for _, summary := range summaries {
+		pieces := []string{summary}
+		if yandexgpt.EstimateTokens(summary) > budget {
+			var err error
+			if pieces, err = splitter.Split(summary); err != nil {
+				return nil, fmt.Errorf("summarize: split summary: %w", err)
+			}
+		}
+		for _, piece := range pieces {
+			n := yandexgpt.EstimateTokens(piece)
+			if len(current) > 0 && tokens+n > budget {
+				groups = append(groups, strings.Join(current, "\n\n"))
+				current, tokens = nil, 0
+			}
+			current = append(current, piece)
+			tokens += n
+		}
+	}
+	if len(current) > 0 {
+		groups = append(groups, strings.Join(current, "\n\n"))
+	}
+	return groups, nil
+}
+
+func estimateAll(texts []string) int {
+	tokens := 0
+	for _, text := range texts {
+		tokens += yandexgpt.EstimateTokens(text)
+	}
+	return tokens
+}
+
+// mapAll summarizes texts with at most Concurrency requests in flight and
+// returns the summaries in input order.
+func (s *summarizer) mapAll(ctx context.Context, texts []string, model, prompt string, options *yandexgpt.CompletionOptions) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]string, len(texts))
+	sem := make(chan struct{}, s.opts.Concurrency)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i, text := range texts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			summary, err := s.generate(ctx, model, options, prompt, text, "")
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = summary
+		}(i, text)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *summarizer) generate(ctx context.Context, model string, options *yandexgpt.CompletionOptions, prompt, text, summary string) (string, error) {
+	content := strings.NewReplacer("{text}", text, "{summary}", summary).Replace(prompt)
+
+	response, err := s.client.GenerateFromMessagesContext(ctx, []yandexgpt.Message{
+		{Role: "user", Text: content},
+	}, model, options, s.opts.CallOptions...)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.usage = s.usage.Add(response.Result.Usage)
+	s.requests++
+	s.mu.Unlock()
+
+	if len(response.Result.Alternatives) == 0 {
+		return "", fmt.Errorf("summarize: model %s returned no alternatives", model)
+	}
+	return strings.TrimSpace(response.Result.Alternatives[0].Message.Text), nil
+}
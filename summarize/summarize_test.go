@@ -0,0 +1,286 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+	"github.com/tigusigalpa/yandexgpt-go/v2/textsplit"
+)
+
+type fakeGenerator struct {
+	mu       sync.Mutex
+	models   []string
+	inFlight int32
+	maxSeen  int32
+	fail     func(prompt string) error
+	reply    func(prompt string) string
+}
+
+func (g *fakeGenerator) GenerateFromMessagesContext(ctx context.Context, messages []yandexgpt.Message, model string, options *yandexgpt.CompletionOptions, opts ...yandexgpt.CallOption) (*yandexgpt.CompletionResponse, error) {
+	n := atomic.AddInt32(&g.inFlight, 1)
+	defer atomic.AddInt32(&g.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&g.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&g.maxSeen, seen, n) {
+			break
+		}
+	}
+
+	g.mu.Lock()
+	g.models = append(g.models, model)
+	g.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prompt := messages[0].Text
+	if g.fail != nil {
+		if err := g.fail(prompt); err != nil {
+			return nil, err
+		}
+	}
+
+	text := "summary"
+	if g.reply != nil {
+		text = g.reply(prompt)
+	}
+	return &yandexgpt.CompletionResponse{
+		Result: yandexgpt.Result{
+			Alternatives: []yandexgpt.Alternative{{Message: yandexgpt.Message{Role: "assistant", Text: text}}},
+			Usage:        yandexgpt.Usage{InputTextTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		},
+	}, nil
+}
+
+func paragraphs(n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("Абзац номер %d.", i)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func TestSummarizeMapReduce(t *testing.T) {
+	gen := &fakeGenerator{}
+
+	result, err := Summarize(context.Background(), gen, paragraphs(10), &Options{
+		Splitter:    textsplit.NewParagraphSplitter(20, 0),
+		Concurrency: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Chunks != 10 {
+		t.Errorf("Expected 10 chunks, got %d", result.Chunks)
+	}
+	if result.Requests != 11 {
+		t.Errorf("Expected 10 map requests and 1 reduce request, got %d", result.Requests)
+	}
+	if result.Usage.TotalTokens != 11*12 {
+		t.Errorf("Expected summed usage %d, got %d", 11*12, result.Usage.TotalTokens)
+	}
+	if gen.maxSeen > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, got %d", gen.maxSeen)
+	}
+
+	last := gen.models[len(gen.models)-1]
+	if last != models.YandexGPT || gen.models[0] != models.YandexGPTLite {
+		t.Errorf("Expected lite for map and full model for reduce, got %v", gen.models)
+	}
+}
+
+func TestSummarizeCollapsesLongSummaries(t *testing.T) {
+	gen := &fakeGenerator{
+		reply: func(string) string { return strings.Repeat("слово ", 10) },
+	}
+
+	result, err := Summarize(context.Background(), gen, paragraphs(8), &Options{
+		Splitter:        textsplit.NewParagraphSplitter(20, 0),
+		MaxReduceTokens: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Requests <= 9 {
+		t.Errorf("Expected extra collapse requests, got %d requests", result.Requests)
+	}
+}
+
+func TestSummarizeSplitsOversizedSummaries(t *testing.T) {
+	reducePrefix := strings.TrimSuffix(DefaultReducePrompt, "{text}")
+	var mu sync.Mutex
+	var reduceInputs []int
+	gen := &fakeGenerator{
+		reply: func(prompt string) string {
+			if !strings.HasPrefix(prompt, reducePrefix) {
+				// Every map summary exceeds MaxReduceTokens on its own.
+				return strings.Repeat("слово ", 40)
+			}
+			mu.Lock()
+			reduceInputs = append(reduceInputs, yandexgpt.EstimateTokens(prompt))
+			mu.Unlock()
+			return "итог"
+		},
+	}
+
+	result, err := Summarize(context.Background(), gen, paragraphs(4), &Options{
+		Splitter:        textsplit.NewParagraphSplitter(20, 0),
+		MaxReduceTokens: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Summary != "итог" || len(reduceInputs) < 2 {
+		t.Fatalf("Expected several reduce requests, got %d", len(reduceInputs))
+	}
+	for _, tokens := range reduceInputs {
+		if tokens > 100 {
+			t.Errorf("Expected every reduce request within MaxReduceTokens, got %v", reduceInputs)
+			break
+		}
+	}
+}
+
+func TestSummarizeCountsReducePrompt(t *testing.T) {
+	reducePrefix := strings.TrimSuffix(DefaultReducePrompt, "{text}")
+	var mu sync.Mutex
+	var reduceInputs []int
+	gen := &fakeGenerator{
+		reply: func(prompt string) string {
+			if strings.HasPrefix(prompt, reducePrefix) {
+				mu.Lock()
+				reduceInputs = append(reduceInputs, yandexgpt.EstimateTokens(prompt))
+				mu.Unlock()
+				return "итог"
+			}
+			return strings.Repeat("слово ", 10)
+		},
+	}
+
+	// Four summaries fit into MaxReduceTokens on their own, but not together
+	// with the prompt.
+	summaries := 4 * yandexgpt.EstimateTokens(strings.Repeat("слово ", 10))
+	maxTokens := summaries + 10
+	if summaries+yandexgpt.EstimateTokens(reducePrefix) <= maxTokens {
+		t.Fatal("Expected the reduce prompt to push the summaries over the limit")
+	}
+
+	result, err := Summarize(context.Background(), gen, paragraphs(4), &Options{
+		Splitter:        textsplit.NewParagraphSplitter(20, 0),
+		MaxReduceTokens: maxTokens,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Summary != "итог" || len(reduceInputs) < 2 {
+		t.Fatalf("Expected several reduce requests, got %v", reduceInputs)
+	}
+	for _, tokens := range reduceInputs {
+		if tokens > maxTokens {
+			t.Errorf("Expected every reduce request within MaxReduceTokens, got %v", reduceInputs)
+			break
+		}
+	}
+}
+
+func TestSummarizeRefineWithinBudget(t *testing.T) {
+	refinePrefix := "Уточни пересказ."
+	var prompts []string
+	gen := &fakeGenerator{
+		reply: func(prompt string) string {
+			prompts = append(prompts, prompt)
+			if strings.HasPrefix(prompt, refinePrefix) {
+				// The running summary grows with every chunk.
+				return strings.TrimPrefix(strings.SplitN(prompt, "|", 2)[0], refinePrefix) + " " + strings.Repeat("слово ", 10)
+			}
+			return "кратко"
+		},
+	}
+
+	result, err := Summarize(context.Background(), gen, paragraphs(20), &Options{
+		Strategy:        Refine,
+		Splitter:        textsplit.NewParagraphSplitter(20, 0),
+		RefinePrompt:    refinePrefix + "{summary}|{text}",
+		ReducePrompt:    "{text}",
+		MaxReduceTokens: 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collapses := 0
+	for _, prompt := range prompts {
+		if !strings.HasPrefix(prompt, refinePrefix) {
+			collapses++
+			continue
+		}
+		if tokens := yandexgpt.EstimateTokens(prompt); tokens > 60 {
+			t.Errorf("Expected every refine request within MaxReduceTokens, got %d tokens", tokens)
+		}
+	}
+	if collapses < 2 {
+		t.Errorf("Expected the running summary to be collapsed, got %d other requests", collapses)
+	}
+	if result.Summary == "" {
+		t.Error("Expected a summary")
+	}
+}
+
+func TestSummarizeRefine(t *testing.T) {
+	var prompts []string
+	gen := &fakeGenerator{
+		reply: func(prompt string) string {
+			prompts = append(prompts, prompt)
+			return fmt.Sprintf("summary-%d", len(prompts))
+		},
+	}
+
+	result, err := Summarize(context.Background(), gen, paragraphs(3), &Options{
+		Strategy:     Refine,
+		Splitter:     textsplit.NewParagraphSplitter(20, 0),
+		RefinePrompt: "{summary}|{text}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Summary != "summary-3" {
+		t.Errorf("Expected summary-3, got %s", result.Summary)
+	}
+	if prompts[2] != "summary-2|Абзац номер 2." {
+		t.Errorf("Unexpected refine prompt %q", prompts[2])
+	}
+}
+
+func TestSummarizeStopsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	gen := &fakeGenerator{
+		fail: func(prompt string) error {
+			if strings.Contains(prompt, "номер 3.") {
+				return boom
+			}
+			return nil
+		},
+	}
+
+	_, err := Summarize(context.Background(), gen, paragraphs(10), &Options{
+		Splitter: textsplit.NewParagraphSplitter(20, 0),
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected boom, got %v", err)
+	}
+}
+
+func TestSummarizeEmptyInput(t *testing.T) {
+	if _, err := Summarize(context.Background(), &fakeGenerator{}, "  ", nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("Expected ErrEmptyInput, got %v", err)
+	}
+}
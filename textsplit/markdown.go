@@ -0,0 +1,154 @@
+package textsplit
+
+import (
+	"strings"
+)
+
+// MarkdownSplitter splits a Markdown document at ATX headings ("# ...")
+// first, so that no chunk mixes text from two sections, and then splits
+// sections that are still too large with Inner. Headings inside fenced code
+// blocks are ignored.
+type MarkdownSplitter struct {
+	// Inner splits sections that exceed its ChunkSize.
+	Inner *RecursiveSplitter
+	// MaxHeadingLevel is the deepest heading level that starts a new
+	// section. Defaults to 6.
+	MaxHeadingLevel int
+	// IncludeHeadings prefixes every chunk with the headings of its section
+	// and all enclosing sections, so each chunk carries its own context.
+	// The prefix counts towards the chunk size.
+	IncludeHeadings bool
+}
+
+// NewMarkdownSplitter returns a Markdown splitter producing chunks of up to
+// chunkSize runes.
+func NewMarkdownSplitter(chunkSize, chunkOverlap int) *MarkdownSplitter {
+	return &MarkdownSplitter{
+		Inner: NewRecursiveSplitter(chunkSize, chunkOverlap),
+	}
+}
+
+type markdownSection struct {
+	headings []string
+	body     string
+}
+
+// Split implements Splitter.
+func (s *MarkdownSplitter) Split(text string) ([]string, error) {
+	if s.Inner == nil {
+		return nil, ErrInvalidChunkSize
+	}
+
+	var chunks []string
+	for _, section := range s.sections(text) {
+		inner := *s.Inner
+		body := section.body
+		prefix := ""
+
+		if s.IncludeHeadings && len(section.headings) > 0 {
+			prefix = strings.Join(section.headings, "\n") + "\n\n"
+			size, err := inner.measure(prefix)
+			if err != nil {
+				return nil, err
+			}
+			if size < inner.ChunkSize {
+				inner.ChunkSize -= size
+				if inner.ChunkOverlap >= inner.ChunkSize {
+					inner.ChunkOverlap = inner.ChunkSize - 1
+				}
+			} else {
+				prefix = ""
+			}
+		} else if len(section.headings) > 0 {
+			body = section.headings[len(section.headings)-1] + "\n" + body
+		}
+
+		parts, err := inner.Split(body)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			chunks = append(chunks, strings.TrimSpace(prefix+part))
+		}
+	}
+	return chunks, nil
+}
+
+func (s *MarkdownSplitter) sections(text string) []markdownSection {
+	maxLevel := s.MaxHeadingLevel
+	if maxLevel <= 0 || maxLevel > 6 {
+		maxLevel = 6
+	}
+
+	var sections []markdownSection
+	var path []string
+	var levels []int
+	var body strings.Builder
+	var fence string
+
+	flush := func() {
+		if strings.TrimSpace(body.String()) != "" || len(path) > 0 {
+			sections = append(sections, markdownSection{
+				headings: append([]string(nil), path...),
+				body:     body.String(),
+			})
+		}
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			body.WriteString(line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			body.WriteString(line)
+			continue
+		}
+
+		level := headingLevel(line)
+		if level == 0 || level > maxLevel {
+			body.WriteString(line)
+			continue
+		}
+
+		flush()
+		for len(levels) > 0 && levels[len(levels)-1] >= level {
+			levels = levels[:len(levels)-1]
+			path = path[:len(path)-1]
+		}
+		levels = append(levels, level)
+		path = append(path, trimmed)
+	}
+	flush()
+
+	return sections
+}
+
+// headingLevel returns the level of an ATX heading line, or 0 if the line is
+// not a heading.
+func headingLevel(line string) int {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return 0
+	}
+	line = line[indent:]
+
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0
+	}
+	if rest := line[level:]; rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != '\n' && rest[0] != '\r' {
+		return 0
+	}
+	return level
+}
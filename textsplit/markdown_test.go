@@ -0,0 +1,82 @@
+package textsplit
+
+import (
+	"strings"
+	"testing"
+)
+
+const markdownDoc = `# Руководство
+
+Вступление.
+
+## Установка
+
+Выполните команду:
+
+` + "```" + `
+# это не заголовок
+go get github.com/tigusigalpa/yandexgpt-go/v2
+` + "```" + `
+
+## Использование
+
+Создайте клиента.
+`
+
+func TestMarkdownSplitterSections(t *testing.T) {
+	chunks, err := NewMarkdownSplitter(1000, 0).Split(markdownDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d: %q", len(chunks), chunks)
+	}
+	if !strings.HasPrefix(chunks[0], "# Руководство") {
+		t.Errorf("Unexpected first chunk: %q", chunks[0])
+	}
+	if !strings.Contains(chunks[1], "# это не заголовок") {
+		t.Errorf("Expected code block to stay in the installation section: %q", chunks[1])
+	}
+	if !strings.HasPrefix(chunks[2], "## Использование") {
+		t.Errorf("Unexpected last chunk: %q", chunks[2])
+	}
+}
+
+func TestMarkdownSplitterIncludeHeadings(t *testing.T) {
+	splitter := NewMarkdownSplitter(1000, 0)
+	splitter.IncludeHeadings = true
+
+	chunks, err := splitter.Split(markdownDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := chunks[len(chunks)-1]
+	if !strings.HasPrefix(last, "# Руководство\n## Использование\n\n") {
+		t.Errorf("Expected heading path prefix, got %q", last)
+	}
+}
+
+func TestMarkdownSplitterLargeSection(t *testing.T) {
+	doc := "# Раздел\n\n" + strings.Repeat("Длинное предложение в разделе. ", 20)
+
+	splitter := NewMarkdownSplitter(80, 0)
+	splitter.IncludeHeadings = true
+
+	chunks, err := splitter.Split(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the section to be split, got %d chunks", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "# Раздел") {
+			t.Errorf("Expected chunk to start with its heading: %q", chunk)
+		}
+		if n := len([]rune(chunk)); n > 80 {
+			t.Errorf("Chunk has %d runes, expected at most 80", n)
+		}
+	}
+}
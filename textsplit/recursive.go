@@ -0,0 +1,291 @@
+package textsplit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// Level is a kind of boundary a RecursiveSplitter may cut text at.
+type Level int
+
+const (
+	// Paragraphs cuts at blank lines.
+	Paragraphs Level = iota
+	// Lines cuts at line breaks.
+	Lines
+	// Sentences cuts at sentence boundaries detected by SentenceRules.
+	Sentences
+	// Words cuts at whitespace.
+	Words
+	// Runes cuts between any two runes.
+	Runes
+)
+
+// DefaultLevels is the boundary order used when RecursiveSplitter.Levels is empty.
+var DefaultLevels = []Level{Paragraphs, Lines, Sentences, Words, Runes}
+
+// RecursiveSplitter cuts text at the coarsest boundary that keeps every chunk
+// within ChunkSize and then merges neighbouring pieces back together, so
+// chunks are as large as allowed. Pieces that still do not fit at the last of
+// Levels are cut between runes. Consecutive chunks share up to ChunkOverlap
+// units of trailing context.
+type RecursiveSplitter struct {
+	// ChunkSize is the maximum chunk length, in runes or in tokens when Counter is set.
+	ChunkSize int
+	// ChunkOverlap is how much of the end of a chunk is repeated at the start of the next one.
+	ChunkOverlap int
+	// Levels lists the boundaries to try, coarsest first. Defaults to DefaultLevels.
+	Levels []Level
+	// Counter measures pieces in tokens. Defaults to counting runes.
+	//
+	// Token counts of adjacent pieces are summed rather than re-measured, so
+	// a chunk may be off by a token or two at piece boundaries. Pieces cut
+	// between runes are measured with EstimateCounter instead, so that a long
+	// word does not cost a call of Counter per rune.
+	Counter TokenCounter
+	// SentenceRules is used for the Sentences level. Defaults to DefaultSentenceRules.
+	SentenceRules *SentenceRules
+}
+
+// NewRecursiveSplitter returns a splitter measuring chunks in runes. When no
+// levels are given, DefaultLevels is used.
+func NewRecursiveSplitter(chunkSize, chunkOverlap int, levels ...Level) *RecursiveSplitter {
+	return &RecursiveSplitter{
+		ChunkSize:    chunkSize,
+		ChunkOverlap: chunkOverlap,
+		Levels:       levels,
+	}
+}
+
+// NewSentenceSplitter returns a splitter that packs whole sentences into
+// chunks of up to chunkSize runes, falling back to words and runes only for
+// sentences that are too long on their own.
+func NewSentenceSplitter(chunkSize, chunkOverlap int) *RecursiveSplitter {
+	return NewRecursiveSplitter(chunkSize, chunkOverlap, Sentences, Words, Runes)
+}
+
+// NewParagraphSplitter returns a splitter that packs whole paragraphs into
+// chunks of up to chunkSize runes, falling back to sentences, words and runes
+// for paragraphs that are too long on their own.
+func NewParagraphSplitter(chunkSize, chunkOverlap int) *RecursiveSplitter {
+	return NewRecursiveSplitter(chunkSize, chunkOverlap, Paragraphs, Sentences, Words, Runes)
+}
+
+// NewTokenSplitter returns a splitter whose chunks hold at most maxTokens
+// tokens as measured by counter.
+func NewTokenSplitter(counter TokenCounter, maxTokens, overlapTokens int) *RecursiveSplitter {
+	return &RecursiveSplitter{
+		ChunkSize:    maxTokens,
+		ChunkOverlap: overlapTokens,
+		Counter:      counter,
+	}
+}
+
+// Split implements Splitter.
+func (s *RecursiveSplitter) Split(text string) ([]string, error) {
+	if s.ChunkSize <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	if s.ChunkOverlap < 0 || s.ChunkOverlap >= s.ChunkSize {
+		return nil, ErrInvalidOverlap
+	}
+
+	levels := s.Levels
+	if len(levels) == 0 {
+		levels = DefaultLevels
+	}
+
+	chunks, err := s.split(text, levels, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := chunks[:0]
+	for _, chunk := range chunks {
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			result = append(result, chunk)
+		}
+	}
+	return result, nil
+}
+
+type piece struct {
+	text string
+	size int
+}
+
+// split cuts text at the first of levels, or between runes once levels are
+// exhausted, and merges the pieces. oversized reports that text is known not
+// to fit, so it is not measured again when a level leaves it whole.
+func (s *RecursiveSplitter) split(text string, levels []Level, oversized bool) ([]string, error) {
+	level, rest := Runes, levels
+	if len(levels) > 0 {
+		level, rest = levels[0], levels[1:]
+	}
+
+	var chunks []string
+	var fitting []piece
+
+	parts := s.cut(text, level)
+	for _, part := range parts {
+		var size int
+		var err error
+		switch {
+		case oversized && len(parts) == 1 && level != Runes:
+			size = s.ChunkSize + 1
+		case level == Runes && s.Counter != nil:
+			size = yandexgpt.EstimateTokens(part)
+		default:
+			size, err = s.measure(part)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if size <= s.ChunkSize || level == Runes {
+			fitting = append(fitting, piece{text: part, size: size})
+			continue
+		}
+
+		chunks = append(chunks, s.merge(fitting)...)
+		fitting = nil
+
+		sub, err := s.split(part, rest, true)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, sub...)
+	}
+
+	return append(chunks, s.merge(fitting)...), nil
+}
+
+// merge greedily packs pieces into chunks, carrying up to ChunkOverlap of
+// trailing pieces over into the next chunk.
+func (s *RecursiveSplitter) merge(pieces []piece) []string {
+	var chunks []string
+	var current []piece
+	total := 0
+
+	for _, p := range pieces {
+		if len(current) > 0 && total+p.size > s.ChunkSize {
+			chunks = append(chunks, join(current))
+			for len(current) > 0 && (total > s.ChunkOverlap || total+p.size > s.ChunkSize) {
+				total -= current[0].size
+				current = current[1:]
+			}
+		}
+		current = append(current, p)
+		total += p.size
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, join(current))
+	}
+	return chunks
+}
+
+func join(pieces []piece) string {
+	var b strings.Builder
+	for _, p := range pieces {
+		b.WriteString(p.text)
+	}
+	return b.String()
+}
+
+func (s *RecursiveSplitter) measure(text string) (int, error) {
+	if s.Counter == nil {
+		return utf8.RuneCountInString(text), nil
+	}
+	if strings.TrimSpace(text) == "" {
+		return 0, nil
+	}
+	return s.Counter.CountTokens(text)
+}
+
+// cut splits text at the given level. Separators stay attached to the
+// preceding piece, so concatenating the pieces yields text unchanged.
+func (s *RecursiveSplitter) cut(text string, level Level) []string {
+	switch level {
+	case Paragraphs:
+		return cutAfter(text, paragraphBreakEnd)
+	case Lines:
+		return cutAfter(text, func(runes []rune, i int) int {
+			if runes[i] == '\n' {
+				return i + 1
+			}
+			return -1
+		})
+	case Sentences:
+		rules := s.SentenceRules
+		if rules == nil {
+			rules = &DefaultSentenceRules
+		}
+		return rules.Split(text)
+	case Words:
+		return cutAfter(text, func(runes []rune, i int) int {
+			if !unicode.IsSpace(runes[i]) || i == 0 || unicode.IsSpace(runes[i-1]) {
+				return -1
+			}
+			return skipSpace(runes, i)
+		})
+	default:
+		parts := make([]string, 0, utf8.RuneCountInString(text))
+		for _, r := range text {
+			parts = append(parts, string(r))
+		}
+		return parts
+	}
+}
+
+// cutAfter cuts text wherever end reports the end of a separator starting at
+// rune i. end returns -1 when there is no separator at i.
+func cutAfter(text string, end func(runes []rune, i int) int) []string {
+	runes := []rune(text)
+	var parts []string
+	start := 0
+	for i := 0; i < len(runes); {
+		if j := end(runes, i); j > i {
+			if j < len(runes) {
+				parts = append(parts, string(runes[start:j]))
+				start = j
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	if start < len(runes) {
+		parts = append(parts, string(runes[start:]))
+	}
+	return parts
+}
+
+func skipSpace(runes []rune, i int) int {
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i
+}
+
+// paragraphBreakEnd reports the end of a whitespace run starting at i that
+// contains at least two line breaks.
+func paragraphBreakEnd(runes []rune, i int) int {
+	if runes[i] != '\n' {
+		return -1
+	}
+	end := skipSpace(runes, i)
+	newlines := 0
+	for _, r := range runes[i:end] {
+		if r == '\n' {
+			newlines++
+		}
+	}
+	if newlines < 2 {
+		return -1
+	}
+	return end
+}
@@ -0,0 +1,162 @@
+package textsplit
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRecursiveSplitterKeepsRunesIntact(t *testing.T) {
+	text := strings.Repeat("Съешь же ещё этих мягких французских булок, да выпей чаю. ", 40)
+
+	chunks, err := NewRecursiveSplitter(100, 20).Split(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("Chunk %d is not valid UTF-8", i)
+		}
+		if n := utf8.RuneCountInString(chunk); n > 100 {
+			t.Errorf("Chunk %d has %d runes, expected at most 100", i, n)
+		}
+	}
+}
+
+func TestRecursiveSplitterPrefersParagraphs(t *testing.T) {
+	text := "Первый абзац.\n\nВторой абзац.\n\nТретий абзац."
+
+	chunks, err := NewRecursiveSplitter(30, 0).Split(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"Первый абзац.\n\nВторой абзац.", "Третий абзац."}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %q", len(expected), len(chunks), chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Errorf("Chunk %d = %q, expected %q", i, chunks[i], expected[i])
+		}
+	}
+}
+
+func TestRecursiveSplitterOverlap(t *testing.T) {
+	text := "Раз. Два. Три. Четыре. Пять. Шесть."
+
+	chunks, err := NewSentenceSplitter(15, 8).Split(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(chunks); i++ {
+		prev := strings.Fields(chunks[i-1])
+		if !strings.HasPrefix(chunks[i], prev[len(prev)-1]) {
+			t.Errorf("Chunk %q does not start with the last sentence of %q", chunks[i], chunks[i-1])
+		}
+	}
+}
+
+func TestRecursiveSplitterLongWord(t *testing.T) {
+	chunks, err := NewRecursiveSplitter(4, 0).Split("абвгдежзий")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"абвг", "дежз", "ий"}
+	if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, chunks)
+	}
+}
+
+func TestRecursiveSplitterInvalidOptions(t *testing.T) {
+	if _, err := NewRecursiveSplitter(0, 0).Split("text"); err != ErrInvalidChunkSize {
+		t.Errorf("Expected ErrInvalidChunkSize, got %v", err)
+	}
+	if _, err := NewRecursiveSplitter(10, 10).Split("text"); err != ErrInvalidOverlap {
+		t.Errorf("Expected ErrInvalidOverlap, got %v", err)
+	}
+}
+
+func TestTokenSplitter(t *testing.T) {
+	words := CounterFunc(func(text string) (int, error) {
+		return len(strings.Fields(text)), nil
+	})
+
+	chunks, err := NewTokenSplitter(words, 3, 0).Split("one two three four five six seven")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"one two three", "four five six", "seven"}
+	if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, chunks)
+	}
+}
+
+func TestEstimateCounter(t *testing.T) {
+	n, err := EstimateCounter.CountTokens("Привет, мир!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n <= 0 {
+		t.Errorf("Expected positive estimate, got %d", n)
+	}
+}
+
+func TestRecursiveSplitterCustomLevels(t *testing.T) {
+	// Pieces that do not fit at the last level are cut between runes.
+	text := "Одно очень длинное предложение без точки в конце"
+	chunks, err := NewRecursiveSplitter(10, 0, Paragraphs, Sentences).Split(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		if utf8.RuneCountInString(chunk) > 10 {
+			t.Errorf("Chunk %q exceeds 10 runes", chunk)
+		}
+	}
+	if len(chunks) < 5 {
+		t.Errorf("Expected at least 5 chunks, got %q", chunks)
+	}
+
+	words := CounterFunc(func(text string) (int, error) {
+		return len(strings.Fields(text)), nil
+	})
+	splitter := NewTokenSplitter(words, 3, 0)
+	splitter.Levels = []Level{Paragraphs}
+	chunks, err = splitter.Split("one two three four five")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		if n, _ := EstimateCounter.CountTokens(chunk); n > 3 {
+			t.Errorf("Chunk %q exceeds 3 tokens", chunk)
+		}
+	}
+}
+
+func TestRecursiveSplitterCountsWholePieces(t *testing.T) {
+	// A text without separators is measured once, not once per level or
+	// per rune.
+	calls := 0
+	counter := CounterFunc(func(text string) (int, error) {
+		calls++
+		return utf8.RuneCountInString(text), nil
+	})
+	chunks, err := NewTokenSplitter(counter, 100, 10).Split(strings.Repeat("論", 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call of the counter, got %d", calls)
+	}
+	if len(chunks) < 10 {
+		t.Errorf("Expected at least 10 chunks, got %d", len(chunks))
+	}
+}
@@ -0,0 +1,168 @@
+package textsplit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SentenceRules controls how text is segmented into sentences.
+//
+// A sentence ends after '.', '!', '?' or '…' (optionally followed by closing
+// quotes or brackets) when the next word does not start with a lowercase
+// letter. A run of whitespace containing a blank line always ends a sentence.
+// Single capital letters followed by a period are treated as initials, so
+// "А. С. Пушкин" stays in one sentence.
+type SentenceRules struct {
+	// Abbreviations never end a sentence, because they usually precede a
+	// name or a number: "ул.", "проф.", "рис.", "Dr.". Matching ignores case.
+	Abbreviations []string
+	// NumberAbbreviations end a sentence only when they follow a number, as
+	// "г." does in "в 2020 г." but not in "г. Москва".
+	NumberAbbreviations []string
+}
+
+// DefaultSentenceRules handles Russian and English text.
+var DefaultSentenceRules = SentenceRules{
+	Abbreviations: []string{
+		// Russian
+		"т.е.", "т.к.", "т.н.", "т.ч.", "напр.", "см.", "ср.", "рис.", "табл.", "стр.", "гл.", "п.", "пп.",
+		"ст.", "ч.", "ул.", "пр-т.", "пер.", "д.", "корп.", "кв.", "обл.", "р-н.", "пос.", "им.", "проф.",
+		"акад.", "доц.", "канд.", "тов.", "гр.", "св.", "о.", "оз.", "р.", "ок.", "прим.", "англ.", "лат.",
+		"рус.", "нем.", "франц.", "т.", "тел.", "ред.", "изд.", "сост.",
+		// English
+		"mr.", "mrs.", "ms.", "dr.", "prof.", "sr.", "jr.", "st.", "vs.", "e.g.", "i.e.", "no.", "fig.",
+		"approx.", "inc.", "ltd.", "co.", "corp.",
+	},
+	NumberAbbreviations: []string{"г.", "гг.", "в.", "вв.", "н.э."},
+}
+
+// SplitSentences segments text using DefaultSentenceRules.
+func SplitSentences(text string) []string {
+	return DefaultSentenceRules.Split(text)
+}
+
+// Split segments text into sentences. Whitespace following a sentence stays
+// attached to it, so concatenating the result yields text unchanged.
+func (r *SentenceRules) Split(text string) []string {
+	abbreviations := lowerSet(r.Abbreviations)
+	numberAbbreviations := lowerSet(r.NumberAbbreviations)
+
+	return cutAfter(text, func(runes []rune, i int) int {
+		if unicode.IsSpace(runes[i]) {
+			return paragraphBreakEnd(runes, i)
+		}
+		if !isTerminator(runes[i]) {
+			return -1
+		}
+
+		end := i
+		for end < len(runes) && (isTerminator(runes[end]) || isCloser(runes[end])) {
+			end++
+		}
+		if end < len(runes) && !unicode.IsSpace(runes[end]) {
+			return -1
+		}
+		next := skipSpace(runes, end)
+		if next == len(runes) {
+			return next
+		}
+		if startsLowercase(runes, next) {
+			return -1
+		}
+		if runes[i] == '.' && end == i+1 && isAbbreviation(runes, i, abbreviations, numberAbbreviations) {
+			return -1
+		}
+		return next
+	})
+}
+
+func isTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+func isCloser(r rune) bool {
+	switch r {
+	case '"', '\'', '»', '”', '’', ')', ']':
+		return true
+	}
+	return false
+}
+
+func isDash(r rune) bool {
+	return r == '—' || r == '–' || r == '-'
+}
+
+// startsLowercase reports whether the text at i continues the current
+// sentence. Dialogue attributions such as "— Привет! — сказал он." start
+// with a dash followed by a lowercase word.
+func startsLowercase(runes []rune, i int) bool {
+	if isDash(runes[i]) {
+		j := skipSpace(runes, i+1)
+		if j == i+1 || j == len(runes) {
+			return false
+		}
+		i = j
+	}
+	return unicode.IsLower(runes[i])
+}
+
+// isAbbreviation reports whether the period at index dot closes an
+// abbreviation or an initial rather than a sentence.
+func isAbbreviation(runes []rune, dot int, abbreviations, numberAbbreviations map[string]bool) bool {
+	start := wordStart(runes, dot)
+	word := string(runes[start : dot+1])
+	letters := []rune(strings.TrimSuffix(word, "."))
+
+	if len(letters) == 1 && unicode.IsUpper(letters[0]) {
+		return true
+	}
+
+	// Spaced forms such as "т. е." are looked up as a whole: "т.е.".
+	candidate := strings.ToLower(word)
+	if start >= 2 && runes[start-1] == ' ' && runes[start-2] == '.' {
+		if prev := wordStart(runes, start-2); start-1-prev == 2 {
+			candidate = strings.ToLower(string(runes[prev:start-1]) + word)
+		}
+	}
+
+	if abbreviations[candidate] {
+		return true
+	}
+	if numberAbbreviations[candidate] {
+		return !followsNumber(runes, start)
+	}
+	return false
+}
+
+// wordStart returns the index of the first rune of the word ending at i.
+func wordStart(runes []rune, i int) int {
+	if i < 0 {
+		return 0
+	}
+	for i > 0 && !unicode.IsSpace(runes[i-1]) && !strings.ContainsRune("(«\"„“[", runes[i-1]) {
+		i--
+	}
+	return i
+}
+
+// followsNumber reports whether the word before start is an Arabic or Roman
+// numeral.
+func followsNumber(runes []rune, start int) bool {
+	end := start - 1
+	for end >= 0 && unicode.IsSpace(runes[end]) {
+		end--
+	}
+	if end < 0 {
+		return false
+	}
+	word := string(runes[wordStart(runes, end) : end+1])
+	return strings.Trim(word, "0123456789-–") == "" || strings.Trim(word, "IVXLCDM-–") == ""
+}
+
+func lowerSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[strings.ToLower(w)] = true
+	}
+	return set
+}
@@ -0,0 +1,82 @@
+package textsplit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{
+			name:     "Simple sentences",
+			text:     "Привет. Как дела? Отлично!",
+			expected: []string{"Привет.", "Как дела?", "Отлично!"},
+		},
+		{
+			name:     "Initials",
+			text:     "Роман написал А. С. Пушкин. Он жил в XIX веке.",
+			expected: []string{"Роман написал А. С. Пушкин.", "Он жил в XIX веке."},
+		},
+		{
+			name:     "Abbreviations",
+			text:     "Офис на ул. Ленина, т.е. в центре. Приходите.",
+			expected: []string{"Офис на ул. Ленина, т.е. в центре.", "Приходите."},
+		},
+		{
+			name:     "Spaced abbreviation",
+			text:     "Это яблоки, груши и т. д. Потом купим ещё.",
+			expected: []string{"Это яблоки, груши и т. д.", "Потом купим ещё."},
+		},
+		{
+			name:     "Year versus city",
+			text:     "Это было в 2020 г. Мы переехали в г. Москва.",
+			expected: []string{"Это было в 2020 г.", "Мы переехали в г. Москва."},
+		},
+		{
+			name:     "Dialogue",
+			text:     "— Куда ты? — спросил он. — Домой.",
+			expected: []string{"— Куда ты? — спросил он.", "— Домой."},
+		},
+		{
+			name:     "Quotes and ellipsis",
+			text:     "Он сказал: «Хватит!» Все замолчали… Тишина.",
+			expected: []string{"Он сказал: «Хватит!»", "Все замолчали…", "Тишина."},
+		},
+		{
+			name:     "Decimal numbers",
+			text:     "Число пи равно 3.14. Это известно.",
+			expected: []string{"Число пи равно 3.14.", "Это известно."},
+		},
+		{
+			name:     "English",
+			text:     "Dr. Smith arrived. He was late.",
+			expected: []string{"Dr. Smith arrived.", "He was late."},
+		},
+		{
+			name:     "Blank line",
+			text:     "Заголовок\n\nТекст",
+			expected: []string{"Заголовок", "Текст"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentences := SplitSentences(tt.text)
+			if strings.Join(sentences, "") != tt.text {
+				t.Errorf("Sentences do not concatenate back to the input: %q", sentences)
+			}
+
+			var trimmed []string
+			for _, s := range sentences {
+				trimmed = append(trimmed, strings.TrimSpace(s))
+			}
+			if strings.Join(trimmed, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("SplitSentences(%q) = %q, expected %q", tt.text, trimmed, tt.expected)
+			}
+		})
+	}
+}
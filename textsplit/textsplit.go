@@ -0,0 +1,63 @@
+// Package textsplit splits long texts into chunks that fit into YandexGPT
+// requests.
+//
+// All splitters work on runes, never on bytes, so Cyrillic text is never cut
+// in the middle of a character. They prefer the coarsest boundary that keeps
+// a chunk within the size limit: paragraphs first, then lines, sentences,
+// words and, as a last resort, single runes.
+//
+// Sizes are measured in runes by default. Set a TokenCounter to measure them
+// in model tokens instead:
+//
+//	splitter := textsplit.NewTokenSplitter(
+//	    textsplit.ModelCounter(client, models.YandexGPTLite),
+//	    6000, 200,
+//	)
+//	chunks, err := splitter.Split(longText)
+package textsplit
+
+import (
+	"errors"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// Splitter splits text into chunks.
+type Splitter interface {
+	Split(text string) ([]string, error)
+}
+
+// TokenCounter measures the length of a text in tokens.
+type TokenCounter interface {
+	CountTokens(text string) (int, error)
+}
+
+// CounterFunc adapts an ordinary function to the TokenCounter interface.
+type CounterFunc func(text string) (int, error)
+
+// CountTokens calls f(text).
+func (f CounterFunc) CountTokens(text string) (int, error) {
+	return f(text)
+}
+
+// EstimateCounter counts tokens with yandexgpt.EstimateTokens and never
+// calls the API. It errs on the side of overestimating.
+var EstimateCounter TokenCounter = CounterFunc(func(text string) (int, error) {
+	return yandexgpt.EstimateTokens(text), nil
+})
+
+// ModelCounter counts tokens exactly using the tokenizer of the given model.
+// Every measured piece down to single words costs one API call, so prefer
+// EstimateCounter for large inputs when an approximate budget is good enough.
+func ModelCounter(client *yandexgpt.Client, model string) TokenCounter {
+	return CounterFunc(func(text string) (int, error) {
+		return client.CountTokens(text, model)
+	})
+}
+
+var (
+	// ErrInvalidChunkSize is returned when the chunk size is not positive.
+	ErrInvalidChunkSize = errors.New("textsplit: chunk size must be positive")
+	// ErrInvalidOverlap is returned when the overlap is negative or not smaller than the chunk size.
+	ErrInvalidOverlap = errors.New("textsplit: chunk overlap must be non-negative and smaller than chunk size")
+)
@@ -0,0 +1,64 @@
+package yandexgpt
+
+import (
+	"context"
+	"math"
+	"unicode"
+)
+
+const TokenizeEndpoint = "https://llm.api.cloud.yandex.net/foundationModels/v1/tokenize"
+
+// Tokenize splits text into tokens using the tokenizer of the given model.
+//
+// See https://yandex.cloud/ru/docs/ai-studio/text-generation/api-ref/Tokenizer/tokenize
+func (c *Client) Tokenize(text, model string, opts ...CallOption) (*TokenizeResponse, error) {
+	call := c.newCallOptions(opts)
+	modelURI, err := c.resolveModelURI(model, call.folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	request := TokenizeRequest{
+		ModelURI: modelURI,
+		Text:     text,
+	}
+
+	var response TokenizeResponse
+	if err := c.doRequest(ctx, call, CallTokenize, "POST", TokenizeEndpoint, &request, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// CountTokens returns the exact number of tokens the given model uses for text.
+func (c *Client) CountTokens(text, model string, opts ...CallOption) (int, error) {
+	response, err := c.Tokenize(text, model, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return len(response.Tokens), nil
+}
+
+// EstimateTokens returns a rough, deliberately pessimistic token count for
+// text without calling the API. YandexGPT tokenizers spend noticeably more
+// tokens on Cyrillic than on Latin text, so the two scripts are weighted
+// separately; punctuation and other symbols are counted as one token each.
+func EstimateTokens(text string) int {
+	var tokens float64
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+		case unicode.Is(unicode.Cyrillic, r):
+			tokens += 1.0 / 3
+		case unicode.Is(unicode.Latin, r):
+			tokens += 1.0 / 4
+		case unicode.IsDigit(r):
+			tokens += 1.0 / 2
+		default:
+			tokens++
+		}
+	}
+	return int(math.Ceil(tokens))
+}
@@ -0,0 +1,72 @@
+package yandexgpt
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestTokenize(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foundationModels/v1/tokenize" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test_iam_token" {
+			t.Errorf("Expected Bearer test_iam_token, got %s", r.Header.Get("Authorization"))
+		}
+
+		var req TokenizeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ModelURI != "gpt://test_folder/yandexgpt-lite" {
+			t.Errorf("Unexpected model URI %s", req.ModelURI)
+		}
+
+		json.NewEncoder(w).Encode(TokenizeResponse{
+			Tokens: []Token{
+				{ID: "1", Text: "При"},
+				{ID: "2", Text: "вет"},
+			},
+			ModelVersion: "23.10.2024",
+		})
+	})
+
+	count, err := client.CountTokens("Привет", models.YandexGPTLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 tokens, got %d", count)
+	}
+}
+
+func TestTokenizeInvalidModel(t *testing.T) {
+	client, _ := NewClient("test_token", "test_folder")
+
+	if _, err := client.Tokenize("text", "gpt:///yandexgpt"); err == nil {
+		t.Error("Expected error for invalid model")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{"Empty", "", 0},
+		{"Whitespace", "  \n\t", 0},
+		{"Latin", "abcdefgh", 2},
+		{"Cyrillic", "абвгде", 2},
+		{"Punctuation", "!?", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.expected {
+				t.Errorf("EstimateTokens(%q) = %d, expected %d", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
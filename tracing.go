@@ -0,0 +1,243 @@
+package yandexgpt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer used by the package.
+const instrumentationName = "github.com/tigusigalpa/yandexgpt-go/v2"
+
+// Span attributes, following the OpenTelemetry semantic conventions for
+// generative AI and HTTP clients where they exist.
+const (
+	attrGenAISystem             = attribute.Key("gen_ai.system")
+	attrGenAIOperation          = attribute.Key("gen_ai.operation.name")
+	attrGenAIRequestModel       = attribute.Key("gen_ai.request.model")
+	attrGenAIRequestTemperature = attribute.Key("gen_ai.request.temperature")
+	attrGenAIRequestMaxTokens   = attribute.Key("gen_ai.request.max_tokens")
+	attrGenAIFinishReasons      = attribute.Key("gen_ai.response.finish_reasons")
+	attrGenAIInputTokens        = attribute.Key("gen_ai.usage.input_tokens")
+	attrGenAIOutputTokens       = attribute.Key("gen_ai.usage.output_tokens")
+	attrGenAIReasoningTokens    = attribute.Key("gen_ai.usage.reasoning_tokens")
+	attrHTTPMethod              = attribute.Key("http.request.method")
+	attrHTTPStatusCode          = attribute.Key("http.response.status_code")
+	attrServerAddress           = attribute.Key("server.address")
+	attrErrorType               = attribute.Key("error.type")
+	attrRequestID               = attribute.Key("yandexgpt.request_id")
+	attrClientRequestID         = attribute.Key("yandexgpt.client_request_id")
+	attrAttempts                = attribute.Key("yandexgpt.attempts")
+	attrOperationID             = attribute.Key("yandexgpt.operation.id")
+	attrOperationDone           = attribute.Key("yandexgpt.operation.done")
+
+	genAISystem = "yandex"
+)
+
+// WithTracerProvider sets the OpenTelemetry tracer provider of the client.
+// By default the global provider, otel.GetTracerProvider, is used.
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithTextMapPropagator sets the propagator that injects the trace context
+// into request headers. By default the global propagator,
+// otel.GetTextMapPropagator, is used.
+func WithTextMapPropagator(propagator propagation.TextMapPropagator) ClientOption {
+	return func(c *Client) {
+		c.propagator = propagator
+	}
+}
+
+func (c *Client) tracer() trace.Tracer {
+	provider := c.tracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(instrumentationName)
+}
+
+func (c *Client) textMapPropagator() propagation.TextMapPropagator {
+	if c.propagator != nil {
+		return c.propagator
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// tracerFromContext returns the tracer of the span in ctx, for code that has
+// no client at hand, or the global tracer.
+func tracerFromContext(ctx context.Context) trace.Tracer {
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		return span.TracerProvider().Tracer(instrumentationName)
+	}
+	return otel.GetTracerProvider().Tracer(instrumentationName)
+}
+
+// genAIOperation returns the GenAI operation name of kind, or "" for calls
+// that are not model invocations.
+func genAIOperation(kind CallKind) string {
+	switch kind {
+	case CallCompletion:
+		return "chat"
+	case CallTokenize:
+		return "tokenize"
+	case CallTextEmbedding:
+		return "embeddings"
+	case CallTextClassification, CallFewShotClassification:
+		return "classify"
+	case CallImageGeneration:
+		return "generate_image"
+	default:
+		return ""
+	}
+}
+
+// requestModel returns the model name of a typed request body, or "" for
+// requests without a model.
+func requestModel(body interface{}) string {
+	modelURI := requestModelURI(body)
+	if modelURI == "" {
+		return ""
+	}
+	return modelName(modelURI)
+}
+
+// requestModelURI returns the model URI of a request body, or "".
+func requestModelURI(body interface{}) string {
+	var modelURI string
+	switch request := body.(type) {
+	case *CompletionRequest:
+		modelURI = request.ModelURI
+	case *TokenizeRequest:
+		modelURI = request.ModelURI
+	case *TextEmbeddingRequest:
+		modelURI = request.ModelURI
+	case *TextClassificationRequest:
+		modelURI = request.ModelURI
+	case *FewShotClassificationRequest:
+		modelURI = request.ModelURI
+	case *ImageGenerationRequest:
+		modelURI = request.ModelURI
+	}
+	return modelURI
+}
+
+// modelName returns the model name of a model URI such as
+// gpt://folder/yandexgpt-lite/latest, or the deployment of ds://deployment.
+func modelName(modelURI string) string {
+	_, path, ok := strings.Cut(modelURI, "://")
+	if !ok {
+		return modelURI
+	}
+	segments := strings.Split(path, "/")
+	name := segments[0]
+	if len(segments) > 1 {
+		name = segments[1]
+	}
+	name, _, _ = strings.Cut(name, "@")
+	return name
+}
+
+// startSpan starts the span of an API call, named after the GenAI operation
+// and model for model invocations and after the kind of call otherwise.
+func (c *Client) startSpan(ctx context.Context, kind CallKind, method, requestURL string, body interface{}) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attrGenAISystem.String(genAISystem),
+		attrHTTPMethod.String(method),
+	}
+	if u, err := url.Parse(requestURL); err == nil {
+		attrs = append(attrs, attrServerAddress.String(u.Hostname()))
+	}
+
+	if request, ok := body.(*CompletionRequest); ok {
+		attrs = append(attrs,
+			attrGenAIRequestTemperature.Float64(request.CompletionOptions.Temperature),
+			attrGenAIRequestMaxTokens.Int(request.CompletionOptions.MaxTokens),
+		)
+	}
+
+	name := string(kind)
+	if operation := genAIOperation(kind); operation != "" {
+		attrs = append(attrs, attrGenAIOperation.String(operation))
+		name = operation
+		if model := requestModel(body); model != "" {
+			attrs = append(attrs, attrGenAIRequestModel.String(model))
+			name += " " + model
+		}
+	}
+
+	return c.tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records the result of an API call on its span and ends it.
+func endSpan(span trace.Span, result interface{}, err error) {
+	defer span.End()
+
+	var metadata *ResponseMetadata
+	if err != nil {
+		metadata = ErrorMetadata(err)
+	} else if r, ok := result.(interface{ ResponseMetadata() *ResponseMetadata }); ok {
+		metadata = r.ResponseMetadata()
+	}
+	if metadata != nil {
+		span.SetAttributes(
+			attrRequestID.String(metadata.RequestID),
+			attrClientRequestID.String(metadata.ClientRequestID),
+			attrAttempts.Int(metadata.Attempts),
+		)
+		if metadata.StatusCode != 0 {
+			span.SetAttributes(attrHTTPStatusCode.Int(metadata.StatusCode))
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attrErrorType.String(fmt.Sprintf("%T", err)))
+		return
+	}
+
+	switch response := result.(type) {
+	case *CompletionResponse:
+		usage := response.Result.Usage
+		span.SetAttributes(
+			attrGenAIInputTokens.Int(usage.InputTextTokens),
+			attrGenAIOutputTokens.Int(usage.CompletionTokens),
+			attrGenAIFinishReasons.StringSlice(finishReasons(response.Result.Alternatives)),
+		)
+		if usage.ReasoningTokens > 0 {
+			span.SetAttributes(attrGenAIReasoningTokens.Int(usage.ReasoningTokens))
+		}
+	case *Operation:
+		span.SetAttributes(attrOperationID.String(response.ID), attrOperationDone.Bool(response.Done))
+	}
+}
+
+// finishReasons maps the statuses of alternatives to GenAI finish reasons.
+func finishReasons(alternatives []Alternative) []string {
+	reasons := make([]string, len(alternatives))
+	for i, alternative := range alternatives {
+		switch alternative.Status {
+		case AlternativeStatusFinal:
+			reasons[i] = "stop"
+		case AlternativeStatusTruncatedFinal:
+			reasons[i] = "length"
+		case AlternativeStatusContentFilter:
+			reasons[i] = "content_filter"
+		case AlternativeStatusToolCalls:
+			reasons[i] = "tool_calls"
+		default:
+			reasons[i] = strings.ToLower(strings.TrimPrefix(alternative.Status, "ALTERNATIVE_STATUS_"))
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,205 @@
+package yandexgpt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setupTracing makes client record its spans in the returned exporter.
+func setupTracing(client *Client) (*Client, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return client.derive(func(d *Client) {
+		WithTracerProvider(provider)(d)
+		WithTextMapPropagator(propagation.TraceContext{})(d)
+	}), exporter
+}
+
+// findSpan returns the span with the given name.
+func findSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return span
+		}
+		names = append(names, span.Name)
+	}
+	t.Fatalf("Expected a %q span, got %v", name, names)
+	return tracetest.SpanStub{}
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingCompletion(t *testing.T) {
+	var traceparent string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set(HeaderRequestID, "req-1")
+		json.NewEncoder(w).Encode(CompletionResponse{Result: Result{
+			Alternatives: []Alternative{{Message: Message{Role: "assistant", Text: "Hi"}, Status: AlternativeStatusFinal}},
+			Usage:        Usage{InputTextTokens: 5, CompletionTokens: 7, TotalTokens: 12, ReasoningTokens: 3},
+		}})
+	})
+	client, exporter := setupTracing(client)
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, &CompletionOptions{Temperature: 0.3, MaxTokens: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := findSpan(t, exporter, "chat yandexgpt-lite")
+	attrs := spanAttributes(span)
+	checks := map[attribute.Key]interface{}{
+		"gen_ai.operation.name":          "chat",
+		"gen_ai.request.model":           "yandexgpt-lite",
+		"gen_ai.request.temperature":     0.3,
+		"gen_ai.request.max_tokens":      int64(100),
+		"gen_ai.usage.input_tokens":      int64(5),
+		"gen_ai.usage.output_tokens":     int64(7),
+		"gen_ai.usage.reasoning_tokens":  int64(3),
+		"gen_ai.response.finish_reasons": []string{"stop"},
+		"http.response.status_code":      int64(200),
+		"yandexgpt.request_id":           "req-1",
+	}
+	for key, want := range checks {
+		got, ok := attrs[key]
+		if !ok {
+			t.Errorf("Missing attribute %s", key)
+			continue
+		}
+		if g := got.AsInterface(); !equalAttribute(g, want) {
+			t.Errorf("Attribute %s = %v, want %v", key, g, want)
+		}
+	}
+
+	want := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("Expected traceparent %q, got %q", want, traceparent)
+	}
+}
+
+func equalAttribute(got, want interface{}) bool {
+	if w, ok := want.([]string); ok {
+		g, ok := got.([]string)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if g[i] != w[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return got == want
+}
+
+func TestTracingError(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	client, exporter := setupTracing(client)
+
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+
+	span := findSpan(t, exporter, "chat yandexgpt-lite")
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected error status, got %v", span.Status)
+	}
+	attrs := spanAttributes(span)
+	if got := attrs["error.type"].AsString(); got != "*yandexgpt.RateLimitError" {
+		t.Errorf("Unexpected error.type %q", got)
+	}
+	if got := attrs["http.response.status_code"].AsInt64(); got != http.StatusTooManyRequests {
+		t.Errorf("Unexpected status code %d", got)
+	}
+}
+
+func TestTracingIAMRefreshAndConversations(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"c1"}`))
+	})
+	client, exporter := setupTracing(client)
+
+	if _, err := client.Conversations().Get("c1"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := findSpan(t, exporter, string(CallGetConversation))
+	iam := findSpan(t, exporter, "createIamToken")
+	if iam.Parent.SpanID() != get.SpanContext.SpanID() {
+		t.Error("Expected the IAM refresh to be a child of the call span")
+	}
+	if _, ok := spanAttributes(get)["gen_ai.operation.name"]; ok {
+		t.Error("Expected no GenAI operation on a conversations span")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestIAMRefreshFollowsClient(t *testing.T) {
+	var iamTraceparent string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"c1"}`))
+	})
+	client, exporter := setupTracing(client)
+
+	// The exchange goes through the HTTP client and propagator of the
+	// derived client, not those the credentials were created with.
+	var exchanges int
+	transport := client.httpClient.Transport
+	client = client.WithHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/iam/v1/tokens" {
+			exchanges++
+			iamTraceparent = r.Header.Get("traceparent")
+		}
+		return transport.RoundTrip(r)
+	})})
+
+	if _, err := client.Conversations().Get("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if exchanges != 1 {
+		t.Errorf("Expected 1 IAM exchange through the client transport, got %d", exchanges)
+	}
+	iam := findSpan(t, exporter, "createIamToken")
+	if want := iam.SpanContext.TraceID().String(); !strings.Contains(iamTraceparent, want) {
+		t.Errorf("Expected the IAM request to carry trace %s, got traceparent %q", want, iamTraceparent)
+	}
+}
+
+func TestModelName(t *testing.T) {
+	tests := map[string]string{
+		"gpt://b1g/yandexgpt-lite/latest":      "yandexgpt-lite",
+		"gpt://b1g/yandexgpt-lite/latest@tune": "yandexgpt-lite",
+		"art://b1g/yandex-art/latest":          "yandex-art",
+		"ds://bt1deployment":                   "bt1deployment",
+	}
+	for uri, want := range tests {
+		if got := modelName(uri); got != want {
+			t.Errorf("modelName(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
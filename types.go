@@ -11,10 +11,10 @@ type ReasoningOptions struct {
 }
 
 type CompletionOptions struct {
-	Stream           bool               `json:"stream"`
-	Temperature      float64            `json:"temperature"`
-	MaxTokens        int                `json:"maxTokens"`
-	ReasoningOptions *ReasoningOptions  `json:"reasoningOptions,omitempty"`
+	Stream           bool              `json:"stream"`
+	Temperature      float64           `json:"temperature"`
+	MaxTokens        int               `json:"maxTokens"`
+	ReasoningOptions *ReasoningOptions `json:"reasoningOptions,omitempty"`
 }
 
 type CompletionRequest struct {
@@ -23,6 +23,15 @@ type CompletionRequest struct {
 	Messages          []Message         `json:"messages"`
 }
 
+// Alternative statuses reported in Alternative.Status.
+const (
+	AlternativeStatusPartial        = "ALTERNATIVE_STATUS_PARTIAL"
+	AlternativeStatusTruncatedFinal = "ALTERNATIVE_STATUS_TRUNCATED_FINAL"
+	AlternativeStatusFinal          = "ALTERNATIVE_STATUS_FINAL"
+	AlternativeStatusContentFilter  = "ALTERNATIVE_STATUS_CONTENT_FILTER"
+	AlternativeStatusToolCalls      = "ALTERNATIVE_STATUS_TOOL_CALLS"
+)
+
 type Alternative struct {
 	Message Message `json:"message"`
 	Status  string  `json:"status"`
@@ -35,6 +44,16 @@ type Usage struct {
 	ReasoningTokens  int `json:"reasoningTokens,omitempty"`
 }
 
+// Add returns the element-wise sum of u and other.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTextTokens:  u.InputTextTokens + other.InputTextTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+		ReasoningTokens:  u.ReasoningTokens + other.ReasoningTokens,
+	}
+}
+
 type Result struct {
 	Alternatives []Alternative `json:"alternatives"`
 	Usage        Usage         `json:"usage"`
@@ -42,9 +61,73 @@ type Result struct {
 }
 
 type CompletionResponse struct {
+	withMetadata
+
 	Result Result `json:"result"`
 }
 
+type TokenizeRequest struct {
+	ModelURI string `json:"modelUri"`
+	Text     string `json:"text"`
+}
+
+type Token struct {
+	ID      string `json:"id"`
+	Text    string `json:"text"`
+	Special bool   `json:"special"`
+}
+
+type TokenizeResponse struct {
+	withMetadata
+
+	Tokens       []Token `json:"tokens"`
+	ModelVersion string  `json:"modelVersion"`
+}
+
+type TextEmbeddingRequest struct {
+	ModelURI string `json:"modelUri"`
+	Text     string `json:"text"`
+}
+
+type TextEmbeddingResponse struct {
+	withMetadata
+
+	Embedding []float64 `json:"embedding"`
+	// NumTokens is an int64 encoded as a string, as in the API.
+	NumTokens    string `json:"numTokens"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+type ClassificationSample struct {
+	Text  string `json:"text"`
+	Label string `json:"label"`
+}
+
+type FewShotClassificationRequest struct {
+	ModelURI        string                 `json:"modelUri"`
+	TaskDescription string                 `json:"taskDescription"`
+	Labels          []string               `json:"labels"`
+	Text            string                 `json:"text"`
+	Samples         []ClassificationSample `json:"samples,omitempty"`
+}
+
+type TextClassificationRequest struct {
+	ModelURI string `json:"modelUri"`
+	Text     string `json:"text"`
+}
+
+type ClassificationPrediction struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+type ClassificationResponse struct {
+	withMetadata
+
+	Predictions  []ClassificationPrediction `json:"predictions"`
+	ModelVersion string                     `json:"modelVersion"`
+}
+
 type GenerationOptions struct {
 	Seed        *int    `json:"seed,omitempty"`
 	AspectRatio *string `json:"aspectRatio,omitempty"`
@@ -71,8 +154,9 @@ type OperationMetadata struct {
 }
 
 type OperationError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int                      `json:"code"`
+	Message string                   `json:"message"`
+	Details []map[string]interface{} `json:"details,omitempty"`
 }
 
 type ImageResponse struct {
@@ -80,6 +164,8 @@ type ImageResponse struct {
 }
 
 type Operation struct {
+	withMetadata
+
 	ID          string             `json:"id"`
 	Description string             `json:"description"`
 	CreatedAt   string             `json:"createdAt"`
@@ -92,6 +178,8 @@ type Operation struct {
 }
 
 type ImageGenerationResult struct {
+	withMetadata
+
 	OperationID string
 	ImageBase64 string
 }
@@ -99,13 +187,17 @@ type ImageGenerationResult struct {
 // Conversations API types
 
 type Conversation struct {
-	ID        string                 `json:"id"`
-	Object    string                 `json:"object"`
-	Metadata  map[string]string      `json:"metadata,omitempty"`
-	CreatedAt int64                  `json:"created_at"`
+	withMetadata
+
+	ID        string            `json:"id"`
+	Object    string            `json:"object"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt int64             `json:"created_at"`
 }
 
 type ConversationDeleted struct {
+	withMetadata
+
 	Object  string `json:"object"`
 	Deleted bool   `json:"deleted"`
 	ID      string `json:"id"`
@@ -117,6 +209,8 @@ type ConversationContentPart struct {
 }
 
 type ConversationItem struct {
+	withMetadata
+
 	Type    string                    `json:"type"`
 	ID      string                    `json:"id,omitempty"`
 	Status  string                    `json:"status,omitempty"`
@@ -125,6 +219,8 @@ type ConversationItem struct {
 }
 
 type ConversationItemsList struct {
+	withMetadata
+
 	Object  string             `json:"object"`
 	Data    []ConversationItem `json:"data"`
 	HasMore bool               `json:"has_more"`
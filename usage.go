@@ -0,0 +1,288 @@
+package yandexgpt
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+// Price is the cost of using a model.
+type Price struct {
+	Currency string `json:"currency"`
+	// InputPer1K is the price of 1000 input tokens.
+	InputPer1K float64 `json:"inputPer1K"`
+	// CompletionPer1K is the price of 1000 completion tokens. Reasoning
+	// tokens are part of the completion tokens and are not priced apart.
+	CompletionPer1K float64 `json:"completionPer1K"`
+	// PerImage is the price of an image generation.
+	PerImage float64 `json:"perImage"`
+}
+
+// PriceTable holds prices keyed by model name, such as "yandexgpt-lite".
+type PriceTable map[string]Price
+
+// DefaultPriceTable returns the synchronous prices of the models in
+// models.DefaultRegistry, applied to input and completion tokens alike.
+func DefaultPriceTable() PriceTable {
+	table := PriceTable{}
+	for _, info := range models.DefaultRegistry.All() {
+		table[info.Name] = Price{
+			Currency:        info.Pricing.Currency,
+			InputPer1K:      info.Pricing.SyncPer1K,
+			CompletionPer1K: info.Pricing.SyncPer1K,
+		}
+	}
+	return table
+}
+
+// UsageOptions configures a UsageTracker. The zero value is usable.
+type UsageOptions struct {
+	// Prices defaults to DefaultPriceTable. Models without a price are
+	// tracked at zero cost.
+	Prices PriceTable
+	// TagKeys restricts grouping to these tags of WithTags; other tags are
+	// ignored. By default every tag is used, so avoid tagging calls with
+	// unique values such as request IDs.
+	TagKeys []string
+}
+
+// UsageRecord is the usage of a model in a folder by calls with the same
+// tags.
+type UsageRecord struct {
+	Model string `json:"model"`
+	// FolderID is the folder of the model URI, which is not the client's
+	// for catalog overrides and models of other folders.
+	FolderID string            `json:"folderId"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	// Requests is the number of successful calls.
+	Requests int   `json:"requests"`
+	Usage    Usage `json:"usage"`
+	// Images is the number of accepted image generations.
+	Images   int     `json:"images"`
+	Cost     float64 `json:"cost"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// UsageTracker aggregates the tokens and image generations of the clients
+// it is attached to with WithUsageTracker, by model, folder and tags, and
+// prices them. Only successful responses are counted: failed attempts,
+// canceled hedges and responses returned by the cache or by interceptors
+// without calling the API are not. It is safe for concurrent use.
+type UsageTracker struct {
+	prices  PriceTable
+	tagKeys []string
+
+	mu      sync.Mutex
+	records map[string]*UsageRecord
+	since   time.Time
+}
+
+// NewUsageTracker returns an empty tracker. opts may be nil.
+func NewUsageTracker(opts *UsageOptions) *UsageTracker {
+	t := &UsageTracker{records: map[string]*UsageRecord{}, since: time.Now()}
+	if opts != nil {
+		t.prices = opts.Prices
+		t.tagKeys = append([]string(nil), opts.TagKeys...)
+	}
+	if t.prices == nil {
+		t.prices = DefaultPriceTable()
+	}
+	return t
+}
+
+// WithUsageTracker attaches tracker to the client. Several clients may share
+// a tracker.
+func WithUsageTracker(tracker *UsageTracker) ClientOption {
+	return func(c *Client) {
+		c.usage = tracker
+	}
+}
+
+// record counts a successful response.
+func (t *UsageTracker) record(call *callOptions, kind CallKind, body, result interface{}) {
+	if t == nil {
+		return
+	}
+
+	var usage Usage
+	var images int
+	switch kind {
+	case CallCompletion:
+		response, ok := result.(*CompletionResponse)
+		if !ok {
+			return
+		}
+		usage = response.Result.Usage
+	case CallImageGeneration:
+		images = 1
+	default:
+		return
+	}
+
+	model := requestModel(body)
+	folderID := modelFolder(requestModelURI(body))
+	if folderID == "" {
+		folderID = call.folderID
+	}
+	tags := t.groupTags(call.tags)
+	key := model + "\x00" + folderID + "\x00" + formatTags(tags)
+	price, priced := t.prices[model]
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.records[key]
+	if !ok {
+		r = &UsageRecord{Model: model, FolderID: folderID, Tags: tags}
+		if priced {
+			r.Currency = price.Currency
+		}
+		t.records[key] = r
+	}
+	r.Requests++
+	r.Usage = r.Usage.Add(usage)
+	r.Images += images
+	r.Cost += float64(usage.InputTextTokens)/1000*price.InputPer1K +
+		float64(usage.CompletionTokens)/1000*price.CompletionPer1K +
+		float64(images)*price.PerImage
+}
+
+// modelFolder returns the folder of a model URI such as
+// gpt://folder/yandexgpt-lite, or "" for URIs without one, such as
+// DataSphere deployments.
+func modelFolder(modelURI string) string {
+	scheme, path, ok := strings.Cut(modelURI, "://")
+	if !ok || scheme == models.SchemeDataSphere {
+		return ""
+	}
+	folder, _, _ := strings.Cut(path, "/")
+	return folder
+}
+
+func (t *UsageTracker) groupTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	grouped := make(map[string]string, len(tags))
+	if len(t.tagKeys) == 0 {
+		for k, v := range tags {
+			grouped[k] = v
+		}
+		return grouped
+	}
+	for _, k := range t.tagKeys {
+		if v, ok := tags[k]; ok {
+			grouped[k] = v
+		}
+	}
+	if len(grouped) == 0 {
+		return nil
+	}
+	return grouped
+}
+
+// formatTags returns tags as "k1=v1;k2=v2", sorted by key.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+// Since returns the time the tracker was created or last reset.
+func (t *UsageTracker) Since() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.since
+}
+
+// Snapshot returns the usage recorded so far, sorted by model, folder and
+// tags.
+func (t *UsageTracker) Snapshot() []UsageRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot()
+}
+
+// Reset clears the tracker and returns the usage recorded until then, so
+// no call is counted twice or lost between a snapshot and a reset.
+func (t *UsageTracker) Reset() []UsageRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := t.snapshot()
+	t.records = map[string]*UsageRecord{}
+	t.since = time.Now()
+	return records
+}
+
+func (t *UsageTracker) snapshot() []UsageRecord {
+	records := make([]UsageRecord, 0, len(t.records))
+	keys := make([]string, 0, len(t.records))
+	for key := range t.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		r := *t.records[key]
+		if r.Tags != nil {
+			tags := make(map[string]string, len(r.Tags))
+			for k, v := range r.Tags {
+				tags[k] = v
+			}
+			r.Tags = tags
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// WriteUsageJSON writes records as a JSON array.
+func WriteUsageJSON(w io.Writer, records []UsageRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// usageCSVHeader lists the columns written by WriteUsageCSV.
+var usageCSVHeader = []string{
+	"model", "folder_id", "tags", "requests", "input_tokens", "completion_tokens",
+	"reasoning_tokens", "total_tokens", "images", "cost", "currency",
+}
+
+// WriteUsageCSV writes records as CSV with a header row. Tags are written in
+// one column as "k1=v1;k2=v2".
+func WriteUsageCSV(w io.Writer, records []UsageRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(usageCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			r.Model,
+			r.FolderID,
+			formatTags(r.Tags),
+			strconv.Itoa(r.Requests),
+			strconv.Itoa(r.Usage.InputTextTokens),
+			strconv.Itoa(r.Usage.CompletionTokens),
+			strconv.Itoa(r.Usage.ReasoningTokens),
+			strconv.Itoa(r.Usage.TotalTokens),
+			strconv.Itoa(r.Images),
+			strconv.FormatFloat(r.Cost, 'f', -1, 64),
+			r.Currency,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
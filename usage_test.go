@@ -0,0 +1,134 @@
+package yandexgpt
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestUsageTracker(t *testing.T) {
+	tracker := NewUsageTracker(&UsageOptions{
+		Prices: PriceTable{
+			models.YandexGPTLite: {Currency: "RUB", InputPer1K: 1, CompletionPer1K: 2},
+			"yandex-art":         {Currency: "RUB", PerImage: 3},
+		},
+		TagKeys: []string{"team"},
+	})
+
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "imageGeneration") {
+			w.Write([]byte(`{"id":"op-1","done":false}`))
+			return
+		}
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeCompletion(w, "ok")
+	}, WithUsageTracker(tracker))
+
+	search := WithTags(map[string]string{"team": "search", "request": "1"})
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil, search); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.WithFolder("other").GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil, search, WithHeader("X-Fail", "1")); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := client.GenerateImageAsync("cat", nil, nil, search); err != nil {
+		t.Fatal(err)
+	}
+
+	records := tracker.Snapshot()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %+v", records)
+	}
+
+	image, other, lite := records[0], records[1], records[2]
+	if image.Model != "yandex-art" || image.Images != 1 || image.Cost != 3 || image.Tags["team"] != "search" {
+		t.Errorf("Unexpected image record: %+v", image)
+	}
+	if other.FolderID != "other" || other.Requests != 1 || other.Tags != nil {
+		t.Errorf("Unexpected record of the other folder: %+v", other)
+	}
+	want := UsageRecord{
+		Model:    models.YandexGPTLite,
+		FolderID: "test_folder",
+		Tags:     map[string]string{"team": "search"},
+		Requests: 2,
+		Usage:    Usage{InputTextTokens: 2, CompletionTokens: 4, TotalTokens: 6},
+		Cost:     0.002 + 0.008,
+		Currency: "RUB",
+	}
+	if lite.Model != want.Model || lite.FolderID != want.FolderID || len(lite.Tags) != 1 || lite.Tags["team"] != "search" ||
+		lite.Requests != want.Requests || lite.Usage != want.Usage || lite.Cost != want.Cost || lite.Currency != want.Currency {
+		t.Errorf("Got %+v, want %+v", lite, want)
+	}
+
+	var csvOut bytes.Buffer
+	if err := WriteUsageCSV(&csvOut, records); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	if len(lines) != 4 || lines[0] != strings.Join(usageCSVHeader, ",") || lines[3] != "yandexgpt-lite,test_folder,team=search,2,2,4,0,6,0,0.01,RUB" {
+		t.Errorf("Unexpected CSV:\n%s", csvOut.String())
+	}
+
+	var jsonOut bytes.Buffer
+	if err := WriteUsageJSON(&jsonOut, records); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []UsageRecord
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil || len(decoded) != 3 || decoded[2].Usage != want.Usage {
+		t.Errorf("Unexpected JSON (%v):\n%s", err, jsonOut.String())
+	}
+
+	since := tracker.Since()
+	if reset := tracker.Reset(); len(reset) != 3 {
+		t.Errorf("Expected Reset to return 3 records, got %d", len(reset))
+	}
+	if len(tracker.Snapshot()) != 0 || !tracker.Since().After(since) {
+		t.Error("Expected an empty tracker after Reset")
+	}
+}
+
+func TestUsageTrackerModelFolder(t *testing.T) {
+	tracker := NewUsageTracker(nil)
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "imageGeneration") {
+			w.Write([]byte(`{"id":"op-1","done":false}`))
+			return
+		}
+		writeCompletion(w, "ok")
+	}, WithUsageTracker(tracker))
+
+	// Usage is recorded in the folder of the model URI, not the client's.
+	if _, err := client.GenerateText("Hello", "gpt://model_folder/yandexgpt-lite", nil); err != nil {
+		t.Fatal(err)
+	}
+	catalogID := "art_folder"
+	if _, err := client.GenerateImageAsync("cat", nil, &catalogID); err != nil {
+		t.Fatal(err)
+	}
+
+	records := tracker.Snapshot()
+	if len(records) != 2 || records[0].FolderID != "art_folder" || records[1].FolderID != "model_folder" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestDefaultPriceTable(t *testing.T) {
+	price, ok := DefaultPriceTable()[models.YandexGPT]
+	info, _ := models.Lookup(models.YandexGPT)
+	if !ok || price.Currency != info.Pricing.Currency || price.InputPer1K != info.Pricing.SyncPer1K || price.CompletionPer1K != info.Pricing.SyncPer1K {
+		t.Errorf("Unexpected default price %+v for %+v", price, info.Pricing)
+	}
+}
@@ -0,0 +1,412 @@
+package yandexgpttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves requests from the cassette and fails requests it
+	// has no response for.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the API and saves them with their
+	// responses to the cassette on Stop.
+	ModeRecord
+	// ModeAuto replays the cassette if its file exists and records it
+	// otherwise.
+	ModeAuto
+)
+
+// Placeholders written to cassettes in place of secrets and folder IDs.
+const (
+	Redacted          = "REDACTED"
+	FolderPlaceholder = "FOLDER_ID"
+)
+
+// secretKeys are JSON keys whose values are always redacted.
+var secretKeys = map[string]bool{
+	"yandexPassportOauthToken": true,
+	"iamToken":                 true,
+	"jwt":                      true,
+}
+
+// cassetteHeaders are the response headers kept in cassettes.
+var cassetteHeaders = []string{"Content-Type", "Retry-After", yandexgpt.HeaderRequestID, yandexgpt.HeaderServerTraceID}
+
+// RecorderOptions configures a Recorder. The zero value is usable.
+type RecorderOptions struct {
+	Mode Mode
+	// Transport sends requests while recording. Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// FolderIDs are replaced with FolderPlaceholder in cassettes and in the
+	// requests matched against them, so replays may use another folder
+	// listed here.
+	FolderIDs []string
+	// Secrets are replaced with Redacted in cassettes. Request headers,
+	// OAuth, IAM and JWT tokens in bodies are never recorded.
+	Secrets []string
+}
+
+// Recorder is an http.RoundTripper that records API exchanges to a cassette
+// file and replays them, for deterministic tests against the real API:
+//
+//	recorder, err := yandexgpttest.NewRecorder("testdata/summary.json", &yandexgpttest.RecorderOptions{
+//	    Mode:      yandexgpttest.ModeAuto,
+//	    FolderIDs: []string{folderID},
+//	})
+//	defer recorder.Stop()
+//
+//	client, err := yandexgpt.NewClientWithHTTPClient(token, folderID, recorder.HTTPClient())
+//
+// Requests are matched on method, path with query and canonical JSON body,
+// after scrubbing. Identical requests get their recorded responses in
+// order, and the last one once those are used up. Streamed responses keep
+// their chunks.
+//
+// Operations are replayed in the last state recorded for them, even when
+// they start, so GenerateImage gets its image without polling or sleeping.
+// Replaying an operation that was not done by the end of the recording
+// fails.
+type Recorder struct {
+	path      string
+	recording bool
+	transport http.RoundTripper
+	replacer  *strings.Replacer
+
+	mu           sync.Mutex
+	interactions []*interaction
+	used         []bool
+}
+
+// interaction is a recorded exchange.
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// Stream holds the chunks of a streamed response instead of Body.
+	Stream []json.RawMessage `json:"stream,omitempty"`
+	// Text holds a body that is not JSON instead of Body.
+	Text string `json:"text,omitempty"`
+}
+
+type cassette struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+// NewRecorder returns a recorder of the cassette at path. opts may be nil.
+// In replay mode the cassette is loaded right away.
+func NewRecorder(path string, opts *RecorderOptions) (*Recorder, error) {
+	if opts == nil {
+		opts = &RecorderOptions{}
+	}
+	r := &Recorder{path: path, transport: opts.Transport}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+	var pairs []string
+	for _, secret := range opts.Secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, Redacted)
+		}
+	}
+	for _, folderID := range opts.FolderIDs {
+		if folderID != "" {
+			pairs = append(pairs, folderID, FolderPlaceholder)
+		}
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+
+	switch opts.Mode {
+	case ModeRecord:
+		r.recording = true
+		return r, nil
+	case ModeAuto:
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			r.recording = true
+			return r, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("yandexgpttest: invalid cassette %s: %w", path, err)
+	}
+	r.interactions = c.Interactions
+	r.used = make([]bool, len(c.Interactions))
+	return r, nil
+}
+
+// Recording reports whether the recorder records rather than replays.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// HTTPClient returns an http.Client using the recorder.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop saves the cassette if the recorder records. Replaying recorders
+// have nothing to save.
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(cassette{Interactions: r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	recorded := recordedRequest{Method: req.Method, URL: r.replacer.Replace(req.URL.String()), Body: r.scrubJSON(body)}
+
+	if r.recording {
+		return r.record(req, body, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, recorded recordedRequest) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	response := recordedResponse{Status: resp.StatusCode, Header: http.Header{}}
+	for _, key := range cassetteHeaders {
+		if v := resp.Header.Get(key); v != "" {
+			response.Header.Set(key, v)
+		}
+	}
+	if chunks := r.scrubStream(data); len(chunks) > 1 {
+		response.Stream = chunks
+	} else if len(chunks) == 1 {
+		response.Body = chunks[0]
+	} else {
+		response.Text = r.replacer.Replace(string(data))
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, &interaction{Request: recorded, Response: response})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, recorded recordedRequest) (*http.Response, error) {
+	key := requestKey(recorded)
+
+	r.mu.Lock()
+	var found *interaction
+	for i, in := range r.interactions {
+		if requestKey(in.Request) != key {
+			continue
+		}
+		found = in
+		if !r.used[i] {
+			r.used[i] = true
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if found == nil {
+		return nil, fmt.Errorf("yandexgpttest: %s has no response for %s %s", r.path, recorded.Method, recorded.URL)
+	}
+	response := found.Response
+	if id, done := operationState(key, response); id != "" && !done {
+		final := r.finalState(id)
+		if _, done := operationState(operationKey(id), final); !done {
+			return nil, fmt.Errorf("yandexgpttest: %s has no done state of operation %s", r.path, id)
+		}
+		response = final
+	}
+
+	// Streamed chunks are written one per line, as the API does.
+	var body bytes.Buffer
+	switch {
+	case response.Stream != nil:
+		for _, chunk := range response.Stream {
+			json.Compact(&body, chunk)
+			body.WriteByte('\n')
+		}
+	case response.Body != nil:
+		json.Compact(&body, response.Body)
+	default:
+		body.WriteString(response.Text)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode:    response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        response.Header.Clone(),
+		ContentLength: int64(body.Len()),
+		Body:          io.NopCloser(&body),
+		Request:       req,
+	}, nil
+}
+
+// finalState returns the last recorded response to a poll of operation id.
+func (r *Recorder) finalState(id string) recordedResponse {
+	key := operationKey(id)
+	var final recordedResponse
+	for _, in := range r.interactions {
+		if requestKey(in.Request) == key {
+			final = in.Response
+		}
+	}
+	return final
+}
+
+// operationState returns the ID of the operation described by the response
+// to the request with key, and whether the operation is done. The ID is
+// empty for responses that are not operations: operations are started by
+// asynchronous calls and polled at the operations endpoint.
+func operationState(key string, response recordedResponse) (id string, done bool) {
+	method, path, _ := strings.Cut(key, " ")
+	path, _, _ = strings.Cut(path, "\n")
+	if response.Status != http.StatusOK ||
+		!(method == http.MethodPost && strings.HasSuffix(path, "Async") || method == http.MethodGet && strings.HasPrefix(path, "/operations/")) {
+		return "", false
+	}
+	var state struct {
+		ID   string `json:"id"`
+		Done bool   `json:"done"`
+	}
+	json.Unmarshal(response.Body, &state)
+	return state.ID, state.Done
+}
+
+// operationKey is the request key of a poll of operation id.
+func operationKey(id string) string {
+	return http.MethodGet + " /operations/" + id + "\n"
+}
+
+// requestKey identifies equal requests: their bodies are canonical JSON,
+// indented in cassettes.
+func requestKey(request recordedRequest) string {
+	var body bytes.Buffer
+	json.Compact(&body, request.Body)
+	path := request.URL
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[j:]
+		} else {
+			path = "/"
+		}
+	}
+	return request.Method + " " + path + "\n" + body.String()
+}
+
+// scrubJSON returns the scrubbed canonical JSON of data, or nil if data is
+// empty. Bodies that are not JSON are kept as JSON strings.
+func (r *Recorder) scrubJSON(data []byte) json.RawMessage {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		v = string(data)
+	}
+	scrubbed, _ := json.Marshal(r.scrub(v))
+	return scrubbed
+}
+
+// scrubStream returns the scrubbed JSON values of data, one per chunk of a
+// streamed response, or nil if data is not JSON.
+func (r *Recorder) scrubStream(data []byte) []json.RawMessage {
+	var chunks []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		var v interface{}
+		err := decoder.Decode(&v)
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			return nil
+		}
+		scrubbed, _ := json.Marshal(r.scrub(v))
+		chunks = append(chunks, scrubbed)
+	}
+}
+
+// scrub redacts secrets and folder IDs in a decoded JSON value.
+func (r *Recorder) scrub(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if secretKeys[key] {
+				v[key] = Redacted
+			} else {
+				v[key] = r.scrub(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = r.scrub(value)
+		}
+	case string:
+		return r.replacer.Replace(v)
+	}
+	return v
+}
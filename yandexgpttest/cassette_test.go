@@ -0,0 +1,172 @@
+package yandexgpttest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+// exercise runs the calls recorded and replayed by TestRecorder.
+func exercise(t *testing.T, recorder *Recorder) (string, string) {
+	t.Helper()
+	client, err := yandexgpt.NewClientWithHTTPClient(DefaultOAuthToken, DefaultFolderID, recorder.HTTPClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replays get the operation done as it starts, without polling.
+	var opts []yandexgpt.CallOption
+	if recorder.Recording() {
+		opts = append(opts, yandexgpt.WithPollInterval(time.Millisecond))
+	}
+	image, err := client.GenerateImage("A cat", nil, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.Result.Alternatives[0].Message.Text, image.ImageBase64
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+
+	server := NewServer(&Options{OperationPolls: 2})
+	server.Script(Reply{Text: "recorded in " + DefaultFolderID})
+	recorder, err := NewRecorder(path, &RecorderOptions{
+		Mode:      ModeAuto,
+		Transport: server.HTTPClient().Transport,
+		FolderIDs: []string{DefaultFolderID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.Recording() {
+		t.Fatal("Expected a missing cassette to be recorded")
+	}
+	text, image := exercise(t, recorder)
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{DefaultOAuthToken, "t1.fake-", DefaultFolderID, "Bearer"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("Expected %q to be scrubbed from the cassette", secret)
+		}
+	}
+
+	// The server is gone: the replay is served from the cassette alone,
+	// without waiting for the operation.
+	recorder, err = NewRecorder(path, &RecorderOptions{Mode: ModeAuto, FolderIDs: []string{DefaultFolderID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Recording() {
+		t.Fatal("Expected an existing cassette to be replayed")
+	}
+	start := time.Now()
+	replayedText, replayedImage := exercise(t, recorder)
+	if replayedText != strings.ReplaceAll(text, DefaultFolderID, FolderPlaceholder) || replayedImage != image {
+		t.Errorf("Unexpected replay %q, %q", replayedText, replayedImage)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the replay not to sleep, took %v", elapsed)
+	}
+
+	client, _ := yandexgpt.NewClientWithHTTPClient(DefaultOAuthToken, DefaultFolderID, recorder.HTTPClient())
+	if _, err := client.GenerateText("Unrecorded", models.YandexGPTLite, nil); err == nil || !strings.Contains(err.Error(), "has no response") {
+		t.Errorf("Expected an unrecorded request to fail, got %v", err)
+	}
+}
+
+func TestRecorderUnfinishedOperation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unfinished.json")
+	server := NewServer(&Options{OperationPolls: 1000})
+	defer server.Close()
+
+	// The recorded run gives up while the operation is still running.
+	recorder, _ := NewRecorder(path, &RecorderOptions{Mode: ModeRecord, Transport: server.HTTPClient().Transport})
+	client, _ := yandexgpt.NewClientWithHTTPClient(DefaultOAuthToken, DefaultFolderID, recorder.HTTPClient())
+	if _, err := client.GenerateImage("A cat", nil, nil, yandexgpt.WithPollInterval(time.Millisecond), yandexgpt.WithTimeout(50*time.Millisecond)); err == nil {
+		t.Fatal("Expected the recorded run to time out")
+	}
+	recorder.Stop()
+
+	// Its replay fails at once instead of polling until the client gives up.
+	recorder, _ = NewRecorder(path, nil)
+	client, _ = yandexgpt.NewClientWithHTTPClient(DefaultOAuthToken, DefaultFolderID, recorder.HTTPClient())
+	start := time.Now()
+	_, err := client.GenerateImage("A cat", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no done state") {
+		t.Errorf("Expected the unfinished operation to fail the replay, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the replay to fail at once, took %v", elapsed)
+	}
+}
+
+func TestRecorderStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stream.json")
+	server := NewServer(nil)
+	defer server.Close()
+	server.Script(Reply{Text: "one two"})
+
+	request := func(recorder *Recorder) []string {
+		body, _ := json.Marshal(yandexgpt.CompletionRequest{
+			ModelURI:          "gpt://" + DefaultFolderID + "/yandexgpt-lite",
+			CompletionOptions: yandexgpt.CompletionOptions{Stream: true, Temperature: 0.3},
+			Messages:          []yandexgpt.Message{{Role: "user", Text: "count"}},
+		})
+		req, _ := http.NewRequest("POST", yandexgpt.CompletionEndpoint, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Api-Key secret")
+		resp, err := recorder.HTTPClient().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var texts []string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var chunk yandexgpt.CompletionResponse
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				t.Fatal(err)
+			}
+			texts = append(texts, chunk.Result.Alternatives[0].Message.Text)
+		}
+		return texts
+	}
+
+	recorder, _ := NewRecorder(path, &RecorderOptions{Mode: ModeRecord, Transport: server.HTTPClient().Transport, Secrets: []string{"count"}})
+	recorded := request(recorder)
+	recorder.Stop()
+
+	var c cassette
+	data, _ := os.ReadFile(path)
+	json.Unmarshal(data, &c)
+	if len(c.Interactions) != 1 || len(c.Interactions[0].Response.Stream) != 2 {
+		t.Fatalf("Expected a streamed response of 2 chunks, got %s", data)
+	}
+	if bytes.Contains(data, []byte("count")) {
+		t.Error("Expected the secret to be scrubbed")
+	}
+
+	recorder, _ = NewRecorder(path, &RecorderOptions{Secrets: []string{"count"}})
+	if replayed := request(recorder); strings.Join(replayed, "|") != strings.Join(recorded, "|") {
+		t.Errorf("Expected the chunks to be replayed, got %q, want %q", replayed, recorded)
+	}
+}
@@ -0,0 +1,91 @@
+package yandexgpttest
+
+import (
+	"net/http"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// Classify sets the function answering classifications. Requests to tuned
+// classifiers are passed with their model URI and text only. By default
+// zero-shot and few-shot requests are answered by Predictions and tuned
+// classifiers predict nothing.
+func (s *Server) Classify(classifier func(request *yandexgpt.FewShotClassificationRequest) []yandexgpt.ClassificationPrediction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.classifier = classifier
+}
+
+// Predictions returns the predictions the server makes for text. A label
+// scores the lowercased words of text it shares with the label and with
+// the samples of the label, and the confidences are the scores plus one,
+// normalized to sum to one. The predictions follow the order of labels.
+func Predictions(text string, labels []string, samples []yandexgpt.ClassificationSample) []yandexgpt.ClassificationPrediction {
+	words := map[string]bool{}
+	for _, word := range lowerWords(text) {
+		words[word] = true
+	}
+
+	scores := make([]float64, len(labels))
+	var total float64
+	for i, label := range labels {
+		known := map[string]bool{}
+		for _, word := range lowerWords(label) {
+			known[word] = true
+		}
+		for _, sample := range samples {
+			if sample.Label == label {
+				for _, word := range lowerWords(sample.Text) {
+					known[word] = true
+				}
+			}
+		}
+		scores[i] = 1
+		for word := range known {
+			if words[word] {
+				scores[i]++
+			}
+		}
+		total += scores[i]
+	}
+
+	predictions := make([]yandexgpt.ClassificationPrediction, len(labels))
+	for i, label := range labels {
+		predictions[i] = yandexgpt.ClassificationPrediction{Label: label, Confidence: scores[i] / total}
+	}
+	return predictions
+}
+
+func (s *Server) serveClassification(w http.ResponseWriter, kind yandexgpt.CallKind, body []byte) {
+	var request yandexgpt.FewShotClassificationRequest
+	if !decode(w, body, &request) {
+		return
+	}
+	switch {
+	case request.ModelURI == "":
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "modelUri is required")
+		return
+	case request.Text == "":
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "text cannot be empty")
+		return
+	case kind == yandexgpt.CallFewShotClassification && len(request.Labels) == 0:
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "labels cannot be empty")
+		return
+	}
+
+	s.mu.Lock()
+	classifier := s.classifier
+	s.mu.Unlock()
+
+	var predictions []yandexgpt.ClassificationPrediction
+	switch {
+	case classifier != nil:
+		predictions = classifier(&request)
+	case kind == yandexgpt.CallFewShotClassification:
+		predictions = Predictions(request.Text, request.Labels, request.Samples)
+	}
+	if predictions == nil {
+		predictions = []yandexgpt.ClassificationPrediction{}
+	}
+	writeJSON(w, map[string]interface{}{"predictions": predictions, "modelVersion": ModelVersion})
+}
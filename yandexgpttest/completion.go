@@ -0,0 +1,264 @@
+package yandexgpttest
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// ModelVersion is the model version reported by the server.
+const ModelVersion = "fake"
+
+// EmbeddingDimensions is the length of the emulated embeddings.
+const EmbeddingDimensions = 64
+
+// Reply is the response to a completion.
+type Reply struct {
+	Text string
+	// Status defaults to yandexgpt.AlternativeStatusFinal.
+	Status string
+	// Usage defaults to token estimates of the request and Text.
+	Usage *yandexgpt.Usage
+	// Delay postpones the response after the server latency. Streamed
+	// replies spread it evenly between their chunks.
+	Delay time.Duration
+	// Fault fails the completion instead. Asynchronous completions start
+	// and their operation fails with the fault.
+	Fault *Fault
+}
+
+// Script queues replies to the next completions, synchronous, streaming or
+// asynchronous, one reply per completion. Once the queue is empty the
+// responder of Respond answers.
+func (s *Server) Script(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Respond sets the function answering completions that have no scripted
+// reply. By default the text of the last message is echoed.
+func (s *Server) Respond(responder func(request *yandexgpt.CompletionRequest) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = responder
+}
+
+// reply returns the reply to request.
+func (s *Server) reply(request *yandexgpt.CompletionRequest) Reply {
+	s.mu.Lock()
+	responder := s.responder
+	if len(s.replies) > 0 {
+		reply := s.replies[0]
+		s.replies = s.replies[1:]
+		s.mu.Unlock()
+		return reply
+	}
+	s.mu.Unlock()
+
+	if responder != nil {
+		return responder(request)
+	}
+	return Reply{Text: request.Messages[len(request.Messages)-1].Text}
+}
+
+// completionRequest decodes and validates a completion request.
+func completionRequest(w http.ResponseWriter, body []byte) (*yandexgpt.CompletionRequest, bool) {
+	var request yandexgpt.CompletionRequest
+	if !decode(w, body, &request) {
+		return nil, false
+	}
+	switch {
+	case request.ModelURI == "":
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "modelUri is required")
+		return nil, false
+	case len(request.Messages) == 0:
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "messages cannot be empty")
+		return nil, false
+	}
+	for _, m := range request.Messages {
+		if m.Text == "" {
+			writeError(w, http.StatusBadRequest, grpcInvalidArgument, "message text cannot be empty")
+			return nil, false
+		}
+	}
+	return &request, true
+}
+
+// result returns the completion result of reply with text so far.
+func (reply *Reply) result(request *yandexgpt.CompletionRequest, text, status string) yandexgpt.Result {
+	var usage yandexgpt.Usage
+	if reply.Usage != nil {
+		usage = *reply.Usage
+	} else {
+		for _, m := range request.Messages {
+			usage.InputTextTokens += yandexgpt.EstimateTokens(m.Text)
+		}
+		usage.CompletionTokens = yandexgpt.EstimateTokens(text)
+		usage.TotalTokens = usage.InputTextTokens + usage.CompletionTokens
+	}
+	return yandexgpt.Result{
+		Alternatives: []yandexgpt.Alternative{{
+			Message: yandexgpt.Message{Role: "assistant", Text: text},
+			Status:  status,
+		}},
+		Usage:        usage,
+		ModelVersion: ModelVersion,
+	}
+}
+
+func (reply *Reply) status() string {
+	if reply.Status == "" {
+		return yandexgpt.AlternativeStatusFinal
+	}
+	return reply.Status
+}
+
+func (s *Server) serveCompletion(w http.ResponseWriter, r *http.Request, body []byte) {
+	request, ok := completionRequest(w, body)
+	if !ok {
+		return
+	}
+	reply := s.reply(request)
+	if request.CompletionOptions.Stream && reply.Fault == nil {
+		s.streamCompletion(w, r, request, &reply)
+		return
+	}
+	if !sleep(r, reply.Delay) {
+		return
+	}
+	if reply.Fault != nil {
+		reply.Fault.write(w)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"result": reply.result(request, reply.Text, reply.status())})
+}
+
+// streamCompletion writes reply as newline-delimited results, one per word,
+// each holding the text generated so far, as the API does for
+// CompletionOptions.Stream.
+func (s *Server) streamCompletion(w http.ResponseWriter, r *http.Request, request *yandexgpt.CompletionRequest, reply *Reply) {
+	words := strings.SplitAfter(reply.Text, " ")
+	delay := reply.Delay / time.Duration(len(words))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-request-id", "fake-request")
+	flusher, _ := w.(http.Flusher)
+	var text strings.Builder
+	for i, word := range words {
+		if !sleep(r, delay) {
+			return
+		}
+		text.WriteString(word)
+		status := yandexgpt.AlternativeStatusPartial
+		if i == len(words)-1 {
+			status = reply.status()
+		}
+		writeBody(w, map[string]interface{}{"result": reply.result(request, text.String(), status)})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) serveCompletionAsync(w http.ResponseWriter, r *http.Request, body []byte) {
+	request, ok := completionRequest(w, body)
+	if !ok {
+		return
+	}
+	reply := s.reply(request)
+	if !sleep(r, reply.Delay) {
+		return
+	}
+	op := s.startOperation("Async GPT Request")
+	if reply.Fault != nil {
+		op.fail(reply.Fault)
+	} else {
+		op.response = reply.result(request, reply.Text, reply.status())
+	}
+	writeJSON(w, op.json(false))
+}
+
+// serveTokenize splits text into words and the whitespace between them.
+func serveTokenize(w http.ResponseWriter, body []byte) {
+	var request yandexgpt.TokenizeRequest
+	if !decode(w, body, &request) {
+		return
+	}
+	var tokens []yandexgpt.Token
+	for _, text := range splitWords(request.Text) {
+		tokens = append(tokens, yandexgpt.Token{ID: strconv.Itoa(len(tokens) + 1), Text: text})
+	}
+	writeJSON(w, map[string]interface{}{"tokens": tokens, "modelVersion": ModelVersion})
+}
+
+// serveEmbedding returns a deterministic embedding hashing the lowercased
+// words of the text, so texts sharing words are similar.
+func serveEmbedding(w http.ResponseWriter, body []byte) {
+	var request yandexgpt.TextEmbeddingRequest
+	if !decode(w, body, &request) {
+		return
+	}
+	if request.Text == "" {
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "text cannot be empty")
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"embedding":    Embedding(request.Text),
+		"numTokens":    strconv.Itoa(yandexgpt.EstimateTokens(request.Text)),
+		"modelVersion": ModelVersion,
+	})
+}
+
+// Embedding returns the embedding the server computes for text.
+func Embedding(text string) []float64 {
+	vector := make([]float64, EmbeddingDimensions)
+	for _, word := range lowerWords(text) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vector[h.Sum32()%EmbeddingDimensions]++
+	}
+	var norm float64
+	for _, x := range vector {
+		norm += x * x
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector
+}
+
+// lowerWords returns the lowercased runs of letters and digits of text.
+func lowerWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// splitWords splits text into runs of whitespace and of other characters.
+func splitWords(text string) []string {
+	var words []string
+	start, space := 0, false
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != space {
+			words = append(words, text[start:i])
+			start = i
+		}
+		if i == start {
+			space = unicode.IsSpace(r)
+		}
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
@@ -0,0 +1,216 @@
+package yandexgpttest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// Pagination of ListItems.
+const (
+	defaultItemsLimit = 20
+	maxItemsLimit     = 100
+)
+
+// conversation is the state of a conversation. Items are kept oldest first.
+type conversation struct {
+	id        string
+	metadata  map[string]string
+	createdAt int64
+	items     []yandexgpt.ConversationItem
+}
+
+func (c *conversation) json() yandexgpt.Conversation {
+	return yandexgpt.Conversation{ID: c.id, Object: "conversation", Metadata: c.metadata, CreatedAt: c.createdAt}
+}
+
+// conversationKind maps a request to the Conversations API, split into the
+// path segments after the base URL, to its call kind.
+func conversationKind(method string, parts []string) yandexgpt.CallKind {
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		if method == http.MethodPost {
+			return yandexgpt.CallCreateConversation
+		}
+	case len(parts) == 1:
+		switch method {
+		case http.MethodGet:
+			return yandexgpt.CallGetConversation
+		case http.MethodPost:
+			return yandexgpt.CallUpdateConversation
+		case http.MethodDelete:
+			return yandexgpt.CallDeleteConversation
+		}
+	case len(parts) == 2 && parts[1] == "items":
+		switch method {
+		case http.MethodGet:
+			return yandexgpt.CallListItems
+		case http.MethodPost:
+			return yandexgpt.CallCreateItems
+		}
+	case len(parts) == 3 && parts[1] == "items":
+		switch method {
+		case http.MethodGet:
+			return yandexgpt.CallGetItem
+		case http.MethodDelete:
+			return yandexgpt.CallDeleteItem
+		}
+	}
+	return ""
+}
+
+func (s *Server) serveConversations(w http.ResponseWriter, r *http.Request, kind yandexgpt.CallKind, body []byte) {
+	var request struct {
+		Metadata map[string]string            `json:"metadata"`
+		Items    []yandexgpt.ConversationItem `json:"items"`
+	}
+	if len(body) > 0 && !decode(w, body, &request) {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, conversationsPrefix), "/"), "/")
+
+	if kind == yandexgpt.CallCreateConversation {
+		c := &conversation{id: "conv_" + s.nextID(), metadata: request.Metadata, createdAt: time.Now().Unix()}
+		s.addItems(c, request.Items)
+		s.mu.Lock()
+		s.conversations[c.id] = c
+		s.mu.Unlock()
+		writeJSON(w, c.json())
+		return
+	}
+
+	s.mu.Lock()
+	c, ok := s.conversations[parts[0]]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, grpcNotFound, "conversation "+parts[0]+" not found")
+		return
+	}
+
+	switch kind {
+	case yandexgpt.CallCreateItems:
+		writeJSON(w, itemsList(s.addItems(c, request.Items), false))
+		return
+	case yandexgpt.CallListItems:
+		s.serveListItems(w, r, c)
+		return
+	}
+
+	s.mu.Lock()
+	var response interface{}
+	switch kind {
+	case yandexgpt.CallGetConversation:
+		response = c.json()
+	case yandexgpt.CallUpdateConversation:
+		if request.Metadata != nil {
+			c.metadata = request.Metadata
+		}
+		response = c.json()
+	case yandexgpt.CallDeleteConversation:
+		delete(s.conversations, c.id)
+		response = yandexgpt.ConversationDeleted{Object: "conversation.deleted", Deleted: true, ID: c.id}
+	case yandexgpt.CallGetItem, yandexgpt.CallDeleteItem:
+		for i, item := range c.items {
+			if item.ID != parts[2] {
+				continue
+			}
+			if kind == yandexgpt.CallGetItem {
+				response = item
+			} else {
+				c.items = append(c.items[:i:i], c.items[i+1:]...)
+				response = c.json()
+			}
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if response == nil {
+		writeError(w, http.StatusNotFound, grpcNotFound, "item "+parts[2]+" not found")
+		return
+	}
+	writeJSON(w, response)
+}
+
+// addItems appends items to c, assigning IDs and statuses, and returns them.
+func (s *Server) addItems(c *conversation, items []yandexgpt.ConversationItem) []yandexgpt.ConversationItem {
+	added := make([]yandexgpt.ConversationItem, 0, len(items))
+	for _, item := range items {
+		if item.ID == "" {
+			item.ID = "item_" + s.nextID()
+		}
+		if item.Status == "" {
+			item.Status = "completed"
+		}
+		added = append(added, item)
+	}
+	s.mu.Lock()
+	c.items = append(c.items, added...)
+	s.mu.Unlock()
+	return added
+}
+
+// serveListItems lists the items of c newest first, or oldest first with
+// order=asc, starting after the item of the after parameter.
+func (s *Server) serveListItems(w http.ResponseWriter, r *http.Request, c *conversation) {
+	query := r.URL.Query()
+	limit := defaultItemsLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxItemsLimit {
+			writeError(w, http.StatusBadRequest, grpcInvalidArgument, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+	order := query.Get("order")
+	if order == "" {
+		order = "desc"
+	}
+	if order != "asc" && order != "desc" {
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "order must be asc or desc")
+		return
+	}
+
+	s.mu.Lock()
+	items := make([]yandexgpt.ConversationItem, 0, len(c.items))
+	for i := range c.items {
+		if order == "asc" {
+			items = append(items, c.items[i])
+		} else {
+			items = append(items, c.items[len(c.items)-1-i])
+		}
+	}
+	s.mu.Unlock()
+
+	if after := query.Get("after"); after != "" {
+		found := false
+		for i, item := range items {
+			if item.ID == after {
+				items, found = items[i+1:], true
+				break
+			}
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, grpcNotFound, "item "+after+" not found")
+			return
+		}
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	writeJSON(w, itemsList(items, hasMore))
+}
+
+func itemsList(items []yandexgpt.ConversationItem, hasMore bool) yandexgpt.ConversationItemsList {
+	list := yandexgpt.ConversationItemsList{Object: "list", Data: items, HasMore: hasMore}
+	if len(items) > 0 {
+		list.FirstID = items[0].ID
+		list.LastID = items[len(items)-1].ID
+	}
+	return list
+}
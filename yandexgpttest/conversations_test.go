@@ -0,0 +1,72 @@
+package yandexgpttest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+func message(role, text string) yandexgpt.ConversationItem {
+	return yandexgpt.ConversationItem{Type: "message", Role: role, Content: []yandexgpt.ConversationContentPart{{Type: "input_text", Text: text}}}
+}
+
+func TestConversations(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	conversations := server.NewClient().Conversations()
+
+	conv, err := conversations.Create(map[string]string{"title": "Support"}, []yandexgpt.ConversationItem{message("user", "Hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.ID == "" || conv.Object != "conversation" || conv.Metadata["title"] != "Support" {
+		t.Errorf("Unexpected conversation %+v", conv)
+	}
+
+	added, err := conversations.CreateItems(conv.ID, []yandexgpt.ConversationItem{message("assistant", "Hello"), message("user", "Bye")})
+	if err != nil || len(added.Data) != 2 || added.Data[0].ID == "" || added.Data[0].Status != "completed" {
+		t.Fatalf("Unexpected items %+v, %v", added, err)
+	}
+
+	limit, asc := 2, "asc"
+	page, err := conversations.ListItems(conv.ID, &yandexgpt.ListItemsOptions{Limit: &limit, Order: &asc})
+	if err != nil || len(page.Data) != 2 || !page.HasMore || page.Data[0].Content[0].Text != "Hi" {
+		t.Fatalf("Unexpected first page %+v, %v", page, err)
+	}
+	page, err = conversations.ListItems(conv.ID, &yandexgpt.ListItemsOptions{Limit: &limit, Order: &asc, After: &page.LastID})
+	if err != nil || len(page.Data) != 1 || page.HasMore || page.Data[0].Content[0].Text != "Bye" {
+		t.Fatalf("Unexpected second page %+v, %v", page, err)
+	}
+	newest, _ := conversations.ListItems(conv.ID, nil)
+	if len(newest.Data) != 3 || newest.FirstID != page.Data[0].ID {
+		t.Errorf("Expected the newest item first, got %+v", newest)
+	}
+
+	item, err := conversations.GetItem(conv.ID, added.Data[0].ID)
+	if err != nil || item.Role != "assistant" {
+		t.Errorf("Unexpected item %+v, %v", item, err)
+	}
+	if _, err := conversations.DeleteItem(conv.ID, added.Data[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conversations.GetItem(conv.ID, added.Data[0].ID); !errors.Is(err, yandexgpt.ErrNotFound) {
+		t.Errorf("Expected the item to be deleted, got %v", err)
+	}
+
+	updated, err := conversations.Update(conv.ID, map[string]string{"title": "Closed"})
+	if err != nil || updated.Metadata["title"] != "Closed" {
+		t.Errorf("Unexpected update %+v, %v", updated, err)
+	}
+	if got, _ := conversations.Get(conv.ID); got.Metadata["title"] != "Closed" || got.CreatedAt != conv.CreatedAt {
+		t.Errorf("Unexpected conversation %+v", got)
+	}
+
+	deleted, err := conversations.Delete(conv.ID)
+	if err != nil || !deleted.Deleted || deleted.ID != conv.ID {
+		t.Errorf("Unexpected deletion %+v, %v", deleted, err)
+	}
+	if _, err := conversations.Get(conv.ID); !errors.Is(err, yandexgpt.ErrNotFound) {
+		t.Errorf("Expected the conversation to be deleted, got %v", err)
+	}
+}
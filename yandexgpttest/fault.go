@@ -0,0 +1,92 @@
+package yandexgpttest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault is an injected error response, written in the error envelope of the
+// API so that the SDK classifies it as it would a real one.
+type Fault struct {
+	// Status is the HTTP status. Defaults to 500.
+	Status int
+	// GRPCCode defaults to the gRPC code matching Status.
+	GRPCCode int
+	// Message defaults to the text of Status. Messages mentioning the
+	// content filter or a hard quota are classified accordingly.
+	Message string
+	// RetryAfter is sent in the Retry-After header, rounded up to seconds.
+	RetryAfter time.Duration
+	// Delay postpones the response after the server latency.
+	Delay time.Duration
+}
+
+// gRPC status codes written in error responses.
+const (
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcNotFound          = 5
+	grpcPermissionDenied  = 7
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+	grpcUnavailable       = 14
+	grpcUnauthenticated   = 16
+)
+
+func (f *Fault) write(w http.ResponseWriter) {
+	status := f.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	code := f.GRPCCode
+	if code == 0 {
+		code = grpcCode(status)
+	}
+	message := f.Message
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+	}
+	writeError(w, status, code, message)
+}
+
+func grpcCode(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return grpcInvalidArgument
+	case http.StatusUnauthorized:
+		return grpcUnauthenticated
+	case http.StatusForbidden:
+		return grpcPermissionDenied
+	case http.StatusNotFound:
+		return grpcNotFound
+	case http.StatusTooManyRequests:
+		return grpcResourceExhausted
+	case http.StatusNotImplemented:
+		return grpcUnimplemented
+	case http.StatusServiceUnavailable:
+		return grpcUnavailable
+	case http.StatusGatewayTimeout:
+		return grpcDeadlineExceeded
+	}
+	return grpcInternal
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-request-id", "fake-request")
+	w.WriteHeader(status)
+	writeBody(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"grpcCode":   code,
+			"httpCode":   status,
+			"message":    message,
+			"httpStatus": http.StatusText(status),
+			"details":    []interface{}{},
+		},
+	})
+}
@@ -0,0 +1,111 @@
+package yandexgpttest
+
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// defaultImage is a transparent 1x1 PNG.
+var defaultImage, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII=")
+
+// operation is a long-running operation, done after a number of polls.
+type operation struct {
+	id          string
+	description string
+	createdAt   time.Time
+	polls       int
+	response    interface{}
+	err         *yandexgpt.OperationError
+}
+
+// startOperation registers a running operation.
+func (s *Server) startOperation(description string) *operation {
+	op := &operation{
+		id:          "op-" + s.nextID(),
+		description: description,
+		createdAt:   time.Now().UTC(),
+		polls:       s.opts.OperationPolls,
+	}
+	s.mu.Lock()
+	s.operations[op.id] = op
+	s.mu.Unlock()
+	return op
+}
+
+func (op *operation) fail(fault *Fault) {
+	status := fault.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	op.err = &yandexgpt.OperationError{Code: fault.GRPCCode, Message: fault.Message}
+	if op.err.Code == 0 {
+		op.err.Code = grpcCode(status)
+	}
+	if op.err.Message == "" {
+		op.err.Message = http.StatusText(status)
+	}
+}
+
+func (op *operation) json(done bool) map[string]interface{} {
+	v := map[string]interface{}{
+		"id":          op.id,
+		"description": op.description,
+		"createdAt":   op.createdAt.Format(time.RFC3339),
+		"createdBy":   "fake",
+		"modifiedAt":  op.createdAt.Format(time.RFC3339),
+		"done":        done,
+	}
+	switch {
+	case !done:
+	case op.err != nil:
+		v["error"] = op.err
+	default:
+		v["response"] = op.response
+	}
+	return v
+}
+
+func (s *Server) serveOperation(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	op, ok := s.operations[id]
+	done := false
+	if ok {
+		if op.polls > 0 {
+			op.polls--
+		} else {
+			done = true
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, grpcNotFound, "operation "+id+" not found")
+		return
+	}
+	writeJSON(w, op.json(done))
+}
+
+func (s *Server) serveImageGeneration(w http.ResponseWriter, body []byte) {
+	var request yandexgpt.ImageGenerationRequest
+	if !decode(w, body, &request) {
+		return
+	}
+	switch {
+	case request.ModelURI == "":
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "modelUri is required")
+		return
+	case len(request.Messages) == 0:
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "messages cannot be empty")
+		return
+	}
+	op := s.startOperation("Image generation")
+	op.response = map[string]string{
+		"@type":        "type.googleapis.com/yandex.cloud.ai.foundation_models.v1.image_generation.ImageGenerationResponse",
+		"image":        base64.StdEncoding.EncodeToString(s.opts.Image),
+		"modelVersion": ModelVersion,
+	}
+	writeJSON(w, op.json(false))
+}
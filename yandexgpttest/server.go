@@ -0,0 +1,323 @@
+// Package yandexgpttest provides an in-memory fake of the Yandex Cloud AI
+// APIs for hermetic tests of code built on the SDK.
+//
+// The Server emulates the IAM token exchange, synchronous, asynchronous and
+// streaming completions, tokenization, embeddings, classification, image
+// generation, the operations endpoint and the Conversations API with
+// in-memory state. Completions echo the last message unless replies are
+// scripted, classifications score labels by the words they share with the
+// text unless a classifier is set, and faults and latency can be injected
+// per call kind:
+//
+//	server := yandexgpttest.NewServer(nil)
+//	defer server.Close()
+//
+//	server.Script(yandexgpttest.Reply{Text: "Hello!"})
+//	server.Fail(yandexgpt.CallCompletion, yandexgpttest.Fault{Status: http.StatusTooManyRequests})
+//
+//	client := server.NewClient(yandexgpt.WithDefaultCallOptions(yandexgpt.WithRetryPolicy(policy)))
+//	response, err := client.GenerateText("Hi", models.YandexGPTLite, nil)
+//
+// Clients built by NewClient, or with the http.Client of HTTPClient, reach
+// the fake through the production endpoint URLs, which the transport
+// redirects to the server.
+//
+// Recorder complements the fake with tests against the real API: it records
+// API exchanges to cassette files, scrubbed of tokens and folder IDs, and
+// replays them in later runs.
+package yandexgpttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+)
+
+// Defaults used when the corresponding Options field is empty.
+const (
+	DefaultOAuthToken = "test-oauth-token"
+	DefaultFolderID   = "test-folder"
+)
+
+// Options configures a Server. The zero value is usable.
+type Options struct {
+	// OAuthToken is the only OAuth token exchanged for IAM tokens.
+	// Defaults to DefaultOAuthToken.
+	OAuthToken string
+	// FolderID is the folder of clients built by NewClient. Defaults to
+	// DefaultFolderID.
+	FolderID string
+	// Latency delays every response but those of the IAM endpoint.
+	Latency time.Duration
+	// OperationPolls is the number of times an operation is reported as
+	// running before it is done.
+	OperationPolls int
+	// Image is the generated image. Defaults to a 1x1 PNG.
+	Image []byte
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Kind   yandexgpt.CallKind
+	Method string
+	// Path includes the query string, if any.
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Server is a fake of the Yandex Cloud AI APIs. It is safe for concurrent
+// use.
+type Server struct {
+	// URL is the base URL of the server, of the form http://ipaddr:port.
+	URL string
+
+	srv  *httptest.Server
+	opts Options
+
+	mu            sync.Mutex
+	seq           int
+	requests      []Request
+	faults        map[yandexgpt.CallKind][]Fault
+	replies       []Reply
+	responder     func(*yandexgpt.CompletionRequest) Reply
+	classifier    func(*yandexgpt.FewShotClassificationRequest) []yandexgpt.ClassificationPrediction
+	operations    map[string]*operation
+	conversations map[string]*conversation
+}
+
+// NewServer starts a server. The caller should call Close when finished.
+// opts may be nil.
+func NewServer(opts *Options) *Server {
+	s := &Server{
+		faults:        map[yandexgpt.CallKind][]Fault{},
+		operations:    map[string]*operation{},
+		conversations: map[string]*conversation{},
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.OAuthToken == "" {
+		s.opts.OAuthToken = DefaultOAuthToken
+	}
+	if s.opts.FolderID == "" {
+		s.opts.FolderID = DefaultFolderID
+	}
+	if s.opts.Image == nil {
+		s.opts.Image = defaultImage
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down, blocking until all outstanding requests
+// have completed.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// HTTPClient returns an http.Client that sends requests for any host to the
+// server, keeping their path.
+func (s *Server) HTTPClient() *http.Client {
+	target, _ := url.Parse(s.URL)
+	return &http.Client{Transport: &redirectTransport{target: target, base: s.srv.Client().Transport}}
+}
+
+// NewClient returns a client of the server authorized with the OAuth token
+// and folder of the Options.
+func (s *Server) NewClient(opts ...yandexgpt.ClientOption) *yandexgpt.Client {
+	client, err := yandexgpt.NewClientWithHTTPClient(s.opts.OAuthToken, s.opts.FolderID, s.HTTPClient(), opts...)
+	if err != nil {
+		// Unreachable: the OAuth token is never empty.
+		panic(err)
+	}
+	return client
+}
+
+// Requests returns the requests received so far, in order, including the
+// failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Fail makes the next calls of kind fail, one per fault, before they reach
+// the emulated API.
+func (s *Server) Fail(kind yandexgpt.CallKind, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[kind] = append(s.faults[kind], faults...)
+}
+
+// redirectTransport rewrites the scheme and host of every request.
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.base.RoundTrip(req)
+}
+
+// Call kinds of requests the SDK does not send through its call pipeline,
+// for use with Fail and Request.Kind.
+const (
+	// KindIAM is the exchange of OAuth tokens for IAM tokens.
+	KindIAM yandexgpt.CallKind = "iam"
+	// KindCompletionAsync is an asynchronous completion.
+	KindCompletionAsync yandexgpt.CallKind = "completionAsync"
+)
+
+const (
+	llmPrefix           = "/foundationModels/v1/"
+	operationsPrefix    = "/operations/"
+	conversationsPrefix = "/v1/conversations"
+)
+
+// callKind maps a request to the SDK call kind, or returns "" for unknown
+// requests.
+func callKind(method, path string) yandexgpt.CallKind {
+	switch {
+	case path == "/iam/v1/tokens" && method == http.MethodPost:
+		return KindIAM
+	case strings.HasPrefix(path, llmPrefix) && method == http.MethodPost:
+		switch name := strings.TrimPrefix(path, llmPrefix); name {
+		case "completion", "tokenize", "textEmbedding", "textClassification", "fewShotTextClassification", "imageGenerationAsync":
+			return yandexgpt.CallKind(name)
+		case "completionAsync":
+			return KindCompletionAsync
+		}
+	case strings.HasPrefix(path, operationsPrefix) && method == http.MethodGet:
+		return yandexgpt.CallGetOperation
+	case path == conversationsPrefix || strings.HasPrefix(path, conversationsPrefix+"/"):
+		return conversationKind(method, strings.Split(strings.Trim(strings.TrimPrefix(path, conversationsPrefix), "/"), "/"))
+	}
+	return ""
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	kind := callKind(r.Method, r.URL.Path)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Kind: kind, Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header.Clone(), Body: body})
+	var fault *Fault
+	if faults := s.faults[kind]; len(faults) > 0 {
+		fault = &faults[0]
+		s.faults[kind] = faults[1:]
+	}
+	s.mu.Unlock()
+
+	if kind != KindIAM && !sleep(r, s.opts.Latency) {
+		return
+	}
+	if fault != nil {
+		if sleep(r, fault.Delay) {
+			fault.write(w)
+		}
+		return
+	}
+
+	switch {
+	case kind == "":
+		writeError(w, http.StatusNotFound, grpcNotFound, fmt.Sprintf("%s %s is not emulated", r.Method, r.URL.Path))
+		return
+	case kind == KindIAM:
+		s.serveIAM(w, body)
+		return
+	case r.Header.Get("Authorization") == "":
+		writeError(w, http.StatusUnauthorized, grpcUnauthenticated, "the request is not authorized")
+		return
+	}
+
+	switch kind {
+	case yandexgpt.CallCompletion:
+		s.serveCompletion(w, r, body)
+	case KindCompletionAsync:
+		s.serveCompletionAsync(w, r, body)
+	case yandexgpt.CallTokenize:
+		serveTokenize(w, body)
+	case yandexgpt.CallTextEmbedding:
+		serveEmbedding(w, body)
+	case yandexgpt.CallImageGeneration:
+		s.serveImageGeneration(w, body)
+	case yandexgpt.CallGetOperation:
+		s.serveOperation(w, strings.TrimPrefix(r.URL.Path, operationsPrefix))
+	case yandexgpt.CallTextClassification, yandexgpt.CallFewShotClassification:
+		s.serveClassification(w, kind, body)
+	default:
+		s.serveConversations(w, r, kind, body)
+	}
+}
+
+func (s *Server) serveIAM(w http.ResponseWriter, body []byte) {
+	var request struct {
+		YandexPassportOauthToken string `json:"yandexPassportOauthToken"`
+	}
+	json.Unmarshal(body, &request)
+	if request.YandexPassportOauthToken != s.opts.OAuthToken {
+		writeError(w, http.StatusUnauthorized, grpcUnauthenticated, "invalid OAuth token")
+		return
+	}
+	writeJSON(w, map[string]string{
+		"iamToken":  "t1.fake-" + s.nextID(),
+		"expiresAt": time.Now().Add(12 * time.Hour).UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// nextID returns a new identifier unique within the server.
+func (s *Server) nextID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return strconv.Itoa(s.seq)
+}
+
+// sleep waits for d or until the client abandons r, and reports whether the
+// response should still be written.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-request-id", "fake-request")
+	writeBody(w, v)
+}
+
+func writeBody(w io.Writer, v interface{}) {
+	json.NewEncoder(w).Encode(v)
+}
+
+func decode(w http.ResponseWriter, body []byte, v interface{}) bool {
+	if err := json.Unmarshal(body, v); err != nil {
+		writeError(w, http.StatusBadRequest, grpcInvalidArgument, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package yandexgpttest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tigusigalpa/yandexgpt-go/v2"
+	"github.com/tigusigalpa/yandexgpt-go/v2/models"
+)
+
+func TestCompletion(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.NewClient()
+
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := response.Result.Alternatives[0].Message.Text; text != "Hello" {
+		t.Errorf("Expected the prompt to be echoed, got %q", text)
+	}
+	if response.Result.Usage.TotalTokens == 0 || response.Result.ModelVersion != ModelVersion {
+		t.Errorf("Unexpected result %+v", response.Result)
+	}
+
+	server.Script(Reply{Text: "scripted", Usage: &yandexgpt.Usage{InputTextTokens: 1, CompletionTokens: 2, TotalTokens: 3}})
+	server.Respond(func(request *yandexgpt.CompletionRequest) Reply {
+		return Reply{Text: strings.ToUpper(request.Messages[0].Text)}
+	})
+	for _, want := range []string{"scripted", "HELLO"} {
+		response, err := client.GenerateText("hello", models.YandexGPTLite, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := response.Result.Alternatives[0].Message.Text; text != want {
+			t.Errorf("Expected %q, got %q", want, text)
+		}
+	}
+
+	requests := server.Requests()
+	if len(requests) != 4 || requests[0].Kind != KindIAM || requests[1].Kind != yandexgpt.CallCompletion {
+		t.Fatalf("Unexpected requests %+v", requests)
+	}
+	if auth := requests[1].Header.Get("Authorization"); !strings.HasPrefix(auth, "Bearer t1.fake-") {
+		t.Errorf("Expected an IAM token issued by the server, got %q", auth)
+	}
+	var request yandexgpt.CompletionRequest
+	json.Unmarshal(requests[1].Body, &request)
+	if request.ModelURI != "gpt://"+DefaultFolderID+"/yandexgpt-lite" {
+		t.Errorf("Unexpected model URI %q", request.ModelURI)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.NewClient()
+
+	server.Fail(yandexgpt.CallCompletion,
+		Fault{Status: http.StatusTooManyRequests, RetryAfter: 1500 * time.Millisecond},
+		Fault{Status: http.StatusServiceUnavailable},
+	)
+	_, err := client.GenerateText("Hello", models.YandexGPTLite, nil)
+	var rateLimit *yandexgpt.RateLimitError
+	if !errors.As(err, &rateLimit) || rateLimit.RetryAfter != 2*time.Second || rateLimit.RequestID != "fake-request" {
+		t.Errorf("Expected a rate limit error, got %v", err)
+	}
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); !errors.Is(err, yandexgpt.ErrUnavailable) {
+		t.Errorf("Expected an unavailable error, got %v", err)
+	}
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Errorf("Expected faults to be used up, got %v", err)
+	}
+
+	// A scripted fault fails one completion, and retries get past it.
+	server.Script(Reply{Fault: &Fault{Status: http.StatusInternalServerError}}, Reply{Text: "recovered"})
+	response, err := client.GenerateText("Hello", models.YandexGPTLite, nil,
+		yandexgpt.WithRetryPolicy(yandexgpt.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	if err != nil || response.Result.Alternatives[0].Message.Text != "recovered" {
+		t.Errorf("Expected the retry to succeed, got %v", err)
+	}
+
+	if _, err := client.GenerateText("", models.YandexGPTLite, nil); !errors.Is(err, yandexgpt.ErrInvalidArgument) {
+		t.Error("Expected an error for an invalid request")
+	}
+
+	other, _ := yandexgpt.NewClientWithHTTPClient("wrong-token", DefaultFolderID, server.HTTPClient())
+	var authErr *yandexgpt.AuthenticationError
+	if _, err := other.GenerateText("Hello", models.YandexGPTLite, nil); !errors.As(err, &authErr) {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	server := NewServer(&Options{Latency: 200 * time.Millisecond})
+	defer server.Close()
+	client := server.NewClient()
+
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil, yandexgpt.WithTimeout(50*time.Millisecond)); err == nil {
+		t.Error("Expected the call to time out")
+	}
+
+	start := time.Now()
+	server.Script(Reply{Text: "slow", Delay: 100 * time.Millisecond})
+	if _, err := client.GenerateText("Hello", models.YandexGPTLite, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected the latency and the delay to add up, took %v", elapsed)
+	}
+}
+
+// post sends body to the API path of the server.
+func post(t *testing.T, server *Server, path string, body interface{}) *http.Response {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "https://llm.api.cloud.yandex.net"+path, bytes.NewReader(data))
+	req.Header.Set("Authorization", "Api-Key test")
+	resp, err := server.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestStreaming(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	server.Script(Reply{Text: "one two three"})
+
+	resp := post(t, server, "/foundationModels/v1/completion", yandexgpt.CompletionRequest{
+		ModelURI:          "gpt://folder/yandexgpt-lite",
+		CompletionOptions: yandexgpt.CompletionOptions{Stream: true},
+		Messages:          []yandexgpt.Message{{Role: "user", Text: "count"}},
+	})
+	defer resp.Body.Close()
+
+	var chunks []yandexgpt.Alternative
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var chunk yandexgpt.CompletionResponse
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk.Result.Alternatives[0])
+	}
+	if len(chunks) != 3 || chunks[1].Message.Text != "one two " || chunks[1].Status != yandexgpt.AlternativeStatusPartial {
+		t.Fatalf("Unexpected chunks %+v", chunks)
+	}
+	if last := chunks[2]; last.Message.Text != "one two three" || last.Status != yandexgpt.AlternativeStatusFinal {
+		t.Errorf("Unexpected last chunk %+v", last)
+	}
+}
+
+func TestCompletionAsync(t *testing.T) {
+	server := NewServer(&Options{OperationPolls: 1})
+	defer server.Close()
+	client := server.NewClient()
+	server.Script(Reply{Text: "done"}, Reply{Fault: &Fault{Status: http.StatusBadRequest, Message: "bad prompt"}})
+
+	request := yandexgpt.CompletionRequest{ModelURI: "gpt://folder/yandexgpt", Messages: []yandexgpt.Message{{Role: "user", Text: "hi"}}}
+	var started yandexgpt.Operation
+	resp := post(t, server, "/foundationModels/v1/completionAsync", request)
+	json.NewDecoder(resp.Body).Decode(&started)
+	resp.Body.Close()
+	if started.ID == "" || started.Done {
+		t.Fatalf("Unexpected operation %+v", started)
+	}
+
+	if op, err := client.GetOperation(started.ID); err != nil || op.Done {
+		t.Fatalf("Expected a running operation, got %+v, %v", op, err)
+	}
+	var failed yandexgpt.Operation
+	resp = post(t, server, "/foundationModels/v1/completionAsync", request)
+	json.NewDecoder(resp.Body).Decode(&failed)
+	resp.Body.Close()
+
+	var done struct {
+		Done     bool             `json:"done"`
+		Response yandexgpt.Result `json:"response"`
+	}
+	req, _ := http.NewRequest("GET", yandexgpt.OperationsEndpoint+"/"+started.ID, nil)
+	req.Header.Set("Authorization", "Api-Key test")
+	resp, err := server.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&done)
+	resp.Body.Close()
+	if !done.Done || done.Response.Alternatives[0].Message.Text != "done" {
+		t.Errorf("Unexpected operation %+v", done)
+	}
+
+	client.GetOperation(failed.ID)
+	op, err := client.GetOperation(failed.ID)
+	if err != nil || !op.Done || op.Error == nil || op.Error.Code != grpcInvalidArgument || op.Error.Message != "bad prompt" {
+		t.Errorf("Expected a failed operation, got %+v, %v", op, err)
+	}
+}
+
+func TestImageGeneration(t *testing.T) {
+	server := NewServer(&Options{Image: []byte("image")})
+	defer server.Close()
+	client := server.NewClient()
+
+	operation, err := client.GenerateImageAsync("A cat", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err := client.GetOperation(operation.ID)
+	if err != nil || !op.Done || op.Response == nil || op.Response.Image != base64.StdEncoding.EncodeToString([]byte("image")) {
+		t.Errorf("Unexpected operation %+v, %v", op, err)
+	}
+
+	if _, err := client.GetOperation("missing"); !errors.Is(err, yandexgpt.ErrNotFound) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestTokenizeAndEmbed(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.NewClient()
+
+	if n, err := client.CountTokens("Привет,  мир", models.YandexGPTLite); err != nil || n != 3 {
+		t.Errorf("Expected 3 tokens, got %d, %v", n, err)
+	}
+
+	response, err := client.Embed("Refund policy", models.TextSearchQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Embedding) != EmbeddingDimensions {
+		t.Errorf("Expected %d dimensions, got %d", EmbeddingDimensions, len(response.Embedding))
+	}
+	same := Embedding("refund policy!")
+	for i := range same {
+		if same[i] != response.Embedding[i] {
+			t.Fatal("Expected embeddings to ignore case and punctuation")
+		}
+	}
+}
+
+func TestClassification(t *testing.T) {
+	server := NewServer(nil)
+	defer server.Close()
+	client := server.NewClient()
+
+	// Zero-shot labels and few-shot samples score the words they share
+	// with the text.
+	response, err := client.ClassifyText("Route the ticket", []string{"billing", "delivery"}, "Where is my delivery?", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top, _ := response.Top(); top.Label != "delivery" || top.Confidence != 2.0/3 {
+		t.Errorf("Unexpected zero-shot predictions %+v", response.Predictions)
+	}
+	response, err = client.ClassifyText("Route the ticket", []string{"billing", "delivery"}, "I was charged twice", []yandexgpt.ClassificationSample{
+		{Text: "Why was I charged twice?", Label: "billing"},
+		{Text: "The courier is late", Label: "delivery"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top, _ := response.Top(); top.Label != "billing" || response.ModelVersion != ModelVersion {
+		t.Errorf("Unexpected few-shot response %+v", response)
+	}
+	if _, err := client.ClassifyText("Route the ticket", nil, "text", nil); !errors.Is(err, yandexgpt.ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument without labels, got %v", err)
+	}
+
+	server.Classify(func(request *yandexgpt.FewShotClassificationRequest) []yandexgpt.ClassificationPrediction {
+		return []yandexgpt.ClassificationPrediction{{Label: "spam", Confidence: 0.1}, {Label: request.Text, Confidence: 0.9}}
+	})
+	response, err = client.ClassifyWithTunedModel("bt1", "ham")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top, _ := response.Top(); top.Label != "ham" {
+		t.Errorf("Expected the classifier to answer, got %+v", response.Predictions)
+	}
+}